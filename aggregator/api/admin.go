@@ -0,0 +1,291 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+const (
+	// auditBodyLimit is how much of a request body is kept in the audit log
+	auditBodyLimit = 4096
+	auditTimeout   = 5 * time.Second
+
+	// adminAuthenticated is set on the context of requests AdminAuth let through
+	adminAuthenticated = "adminAuthenticated"
+)
+
+// AdminAuth rejects requests without the bearer token of [admin] token with 401, and all
+// requests with 403 if no token is configured.
+func AdminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := core.C.Admin.Token
+		if token == "" {
+			adminError(c, http.StatusForbidden, errors.New("admin API disabled"))
+			c.Abort()
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			adminError(c, http.StatusUnauthorized, errors.New("invalid admin token"))
+			c.Abort()
+			return
+		}
+		c.Set(adminAuthenticated, true)
+		c.Next()
+	}
+}
+
+// AuditRequests writes every request to the audit log once it was handled. Requests AdminAuth
+// rejected go to the separate log of rejected requests, see core.AuditRejected.
+//
+// The actor is taken from the X-Admin-Actor header and defaults to "admin". The header is
+// reported by the client, so it is recorded as the reported actor.
+func AuditRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil {
+			body, _ = io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if len(body) > auditBodyLimit {
+			body = body[:auditBodyLimit]
+		}
+
+		c.Next()
+
+		entry := core.AuditEntry{
+			Time:          time.Now().Unix(),
+			ReportedActor: adminActor(c),
+			Ip:            c.ClientIP(),
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			Body:          string(body),
+			Status:        c.Writer.Status(),
+		}
+		if len(c.Params) > 0 {
+			entry.Params = make(map[string]string, len(c.Params))
+			for _, p := range c.Params {
+				entry.Params[p.Key] = p.Value
+			}
+		}
+		if err := c.Errors.Last(); err != nil {
+			entry.Error = err.Error()
+		}
+		// the client may be gone already, the entry must still be written
+		ctx, cancel := context.WithTimeout(context.Background(), auditTimeout)
+		defer cancel()
+		audit := core.AuditRejected
+		if c.GetBool(adminAuthenticated) {
+			audit = core.Audit
+		}
+		if err := audit(ctx, entry); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to write audit entry, due to {%s}", err))
+		}
+	}
+}
+
+// adminActor returns who made an admin request, from the X-Admin-Actor header.
+func adminActor(c *gin.Context) string {
+	if actor := c.GetHeader("X-Admin-Actor"); actor != "" {
+		return actor
+	}
+	return "admin"
+}
+
+// adminError responds with err, and records it for the audit log.
+func adminError(c *gin.Context, status int, err error) {
+	_ = c.Error(err)
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// adminTaskId parses the taskId parameter, responding with 400 if it is invalid.
+func adminTaskId(c *gin.Context) (uint64, bool) {
+	taskId, err := strconv.ParseUint(c.Param("taskId"), 10, 64)
+	if err != nil {
+		adminError(c, http.StatusBadRequest, errors.New("invalid task id"))
+		return 0, false
+	}
+	return taskId, true
+}
+
+// GetTaskState returns everything stored about a task, as kept in Redis.
+//
+// Returns 404 if nothing is stored about the task.
+func GetTaskState(c *gin.Context) {
+	taskId, ok := adminTaskId(c)
+	if !ok {
+		return
+	}
+	state, err := core.LoadTaskState(c, taskId)
+	if errors.Is(err, core.ErrTaskNotFound) {
+		adminError(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
+
+// ResolveRequest is the body of ResolveTask.
+type ResolveRequest struct {
+	Result   *int64 `json:"result" binding:"required"`
+	Operator string `json:"operator"` // defaults to the task's performer
+	Reason   string `json:"reason" binding:"required"`
+}
+
+// ResolveTask finalizes a task that is not finished yet with the given result and queues it
+// for RespondToTask.
+//
+// The result must be one of the result codes the squaring contract accepts, see
+// core.SubmittableResult. Returns 409 if the task is already finished,
+// and 400 if no operator is given and the performer never submitted.
+func ResolveTask(c *gin.Context) {
+	taskId, ok := adminTaskId(c)
+	if !ok {
+		return
+	}
+	var req ResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminError(c, http.StatusBadRequest, err)
+		return
+	}
+	if !validResult(*req.Result) {
+		adminError(c, http.StatusBadRequest, errors.New("invalid result code"))
+		return
+	}
+	if !core.SubmittableResult(*req.Result) {
+		adminError(c, http.StatusBadRequest, core.ErrResultNotSubmittable)
+		return
+	}
+	operator, err := core.ResolveTask(c, taskId, *req.Result, req.Operator, adminActor(c), req.Reason)
+	switch {
+	case errors.Is(err, core.ErrTaskFinished):
+		adminError(c, http.StatusConflict, err)
+		return
+	case errors.Is(err, core.ErrNoOperator):
+		adminError(c, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"taskId": taskId, "result": *req.Result, "operator": operator})
+}
+
+// RequeueTask queues a finished task for RespondToTask again, rebuilt from its verification data.
+//
+// Returns 409 if the task is waiting for delivery already, has no final outcome or a result
+// that is not submitted, see core.SubmittableResult, and 404 if its verification data expired.
+func RequeueTask(c *gin.Context) {
+	taskId, ok := adminTaskId(c)
+	if !ok {
+		return
+	}
+	pending, err := core.PendingDeliveries(c)
+	if err != nil {
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	if len(pending[taskId]) > 0 {
+		adminError(c, http.StatusConflict, errors.New("task is waiting for delivery already, redrive dead letters instead"))
+		return
+	}
+	task, err := core.RequeueFinishedTask(c, taskId)
+	if err == redis.Nil {
+		adminError(c, http.StatusNotFound, errors.New("verification data not found"))
+		return
+	}
+	if err != nil {
+		adminError(c, http.StatusConflict, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"task": task})
+}
+
+// PurgeTask deletes everything stored about a task, see core.PurgeTask.
+func PurgeTask(c *gin.Context) {
+	taskId, ok := adminTaskId(c)
+	if !ok {
+		return
+	}
+	removed, err := core.PurgeTask(c, taskId)
+	if err != nil {
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"taskId": taskId, "removed": removed})
+}
+
+// GetBannedOperators lists the banned operators.
+func GetBannedOperators(c *gin.Context) {
+	addresses, err := core.BannedOperators(c)
+	if err != nil {
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"banned": addresses})
+}
+
+// BanOperator rejects all further submissions of the operator with 403.
+func BanOperator(c *gin.Context) {
+	address := c.Param("address")
+	changed, err := core.BanOperator(c, address)
+	if err != nil {
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address, "banned": true, "changed": changed})
+}
+
+// UnbanOperator accepts submissions of the operator again.
+func UnbanOperator(c *gin.Context) {
+	address := c.Param("address")
+	changed, err := core.UnbanOperator(c, address)
+	if err != nil {
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address, "banned": false, "changed": changed})
+}
+
+// GetAuditLog returns a page of the audit log, newest first.
+//
+// Query parameters:
+// - offset, limit: the page, limit defaults to 20 and is at most 100
+// - rejected: "true" for the log of requests without a valid token
+func GetAuditLog(c *gin.Context) {
+	offset, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+	entries, total, err := core.AuditLog(c, c.Query("rejected") == "true", offset, limit)
+	if err != nil {
+		adminError(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries, "total": total, "offset": offset, "limit": limit})
+}
+
+func validResult(result int64) bool {
+	switch result {
+	case core.ResultRejected, core.ResultApproved, core.ResultPerformerMissing,
+		core.ResultInsufficientAttestations, core.ResultSplitVote:
+		return true
+	}
+	return false
+}
@@ -1,36 +1,50 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 
 	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/replay"
 	"github.com/satlayer/hello-world-bvs/aggregator/svc"
 	"github.com/satlayer/hello-world-bvs/aggregator/util"
-	"github.com/satlayer/satlayer-api/signer"
+	"github.com/satlayer/hello-world-bvs/payload"
 )
 
-type Payload struct {
-	TaskId    uint64 `json:"taskID" binding:"required"`
-	Result    string `json:"result" binding:"required"`
-	Timestamp int64  `json:"timestamp" binding:"required"`
-	Signature string `json:"signature" binding:"required"`
-	PubKey    string `json:"pubKey" binding:"required"`
-	Role      string `json:"role" binding:"required"`
+// Payload is the signed submission an operator posts, shared with the operator node.
+type Payload = payload.Submission
+
+// guard rejects stale and replayed submissions.
+var guard = replay.NewGuard(replay.NewRedisNonceStore(core.S.RedisConn), time.Duration(core.C.App.ClockSkew)*time.Second)
+
+// SubmitError is a submission rejected by Submit, with the HTTP status it is reported with.
+type SubmitError struct {
+	Status int
+	Err    error
+}
+
+func (e *SubmitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SubmitError) Unwrap() error {
+	return e.Err
+}
+
+func rejectf(status int, format string, args ...interface{}) *SubmitError {
+	return &SubmitError{Status: status, Err: fmt.Errorf(format, args...)}
 }
 
 // Aggregator handles the aggregator endpoint for the API.
 //
-// It parses the payload from the request body and verifies the signature.
-// It checks if the timestamp is within the allowed range.
-// It verifies if the task is finished and if the operator has already sent the task.
-// If all checks pass, it saves the task to the queue.
+// It parses the payload from the request body and passes it to Submit.
 // It returns an HTTP response with the status of the operation.
 //
 // Parameters:
@@ -41,191 +55,227 @@ type Payload struct {
 func Aggregator(c *gin.Context) {
 	var payload Payload
 	if err := c.ShouldBindJSON(&payload); err != nil {
+		if isBodyTooLarge(err) {
+			reject(c, http.StatusRequestEntityTooLarge, RejectBodyTooLarge, "request body too large")
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if payload.Role != core.RolePerformer && payload.Role != core.RoleAttester {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+	outcome, err := Submit(c, &payload)
+	var submitErr *SubmitError
+	if errors.As(err, &submitErr) {
+		c.JSON(submitErr.Status, gin.H{"error": submitErr.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"status": "success", "message": SubmitMessage(outcome.Status)})
+}
 
-	nowTs := time.Now().Unix()
-	if payload.Timestamp > nowTs || payload.Timestamp < nowTs-60*2 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "timestamp out of range"})
-		return
+// SubmitMessage describes the vote status of a task after a submission.
+func SubmitMessage(status string) string {
+	switch status {
+	case core.VotePending:
+		return "waiting for more attestations"
+	case core.VoteApproved, core.VoteRejected:
+		return "task processed"
+	default:
+		return "submission recorded"
+	}
+}
+
+// verifyPayload checks the fields of a payload that need no stored state, and its signature.
+//
+// Returns the operator address of the payload's public key, or a *SubmitError with status 400.
+func verifyPayload(payload *Payload) (string, error) {
+	if payload.Role != core.RolePerformer && payload.Role != core.RoleAttester {
+		return "", rejectf(http.StatusBadRequest, "invalid role")
+	}
+
+	if err := guard.CheckTimestamp(payload.Timestamp); err != nil {
+		return "", &SubmitError{Status: http.StatusBadRequest, Err: err}
 	}
 
 	pubKey, address, err := util.PubKeyToAddress(payload.PubKey)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return "", &SubmitError{Status: http.StatusBadRequest, Err: err}
 	}
 
 	// Validate result format based on role
 	if payload.Role == core.RolePerformer {
 		resultParts := strings.Split(payload.Result, "-")
 		if len(resultParts) != 2 {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid result format for performer"})
-			return
+			return "", rejectf(http.StatusBadRequest, "invalid result format for performer")
 		}
 	} else {
 		if payload.Result != "true" && payload.Result != "false" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid result format for attester"})
-			return
+			return "", rejectf(http.StatusBadRequest, "invalid result format for attester")
 		}
 		fmt.Printf("Attester validation result: %s\n", payload.Result)
 	}
 
-	msgPayload := fmt.Sprintf("%s-%d-%d-%s", core.C.Chain.BvsHash, payload.Timestamp, payload.TaskId, payload.Result)
-	msgBytes := []byte(msgPayload)
-	if isValid, err := signer.VerifySignature(pubKey, msgBytes, payload.Signature); err != nil || !isValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid signature"})
-		return
+	if err := payload.Verify(pubKey, core.C.Chain.Id, core.C.Chain.BvsHash); err != nil {
+		return "", &SubmitError{Status: http.StatusBadRequest, Err: err}
 	}
+	return address, nil
+}
 
-	pkTaskFinished := fmt.Sprintf("%s%d", core.PkTaskFinished, payload.TaskId)
-	if isExist, err := core.S.RedisConn.Exists(c, pkTaskFinished).Result(); err != nil || isExist == 1 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "task already finished"})
-		return
+// Submit verifies a payload and records it as a vote for its task.
+//
+// Submissions beyond the [limits] pubKeyRate of their public key are rejected with 429 before
+// any other check. Submissions rejected before their signature is verified, or with an invalid
+// signature, give their token back, so forged submissions cannot exhaust another operator's limit.
+// It verifies the signature over the payload's canonical encoding.
+// It checks if the timestamp is within the configured clock skew and that the operator's nonce
+// was not used before, and rejects a used nonce with 409.
+// It verifies if the task is finished and if the operator has already sent the task.
+// Performer submissions are only accepted from the operator the task was assigned to.
+// Submissions of operators banned through the admin API are rejected with 403.
+// Performer results are checked against the trusted RPCs if the policy asks for verification.
+// If all checks pass, it records the vote, which queues the task once consensus is reached.
+// The first submission of a task sets its deadline, after which the sweeper finalizes it
+// without consensus.
+// Both the REST and the gRPC API submit through it.
+//
+// Parameters:
+// - ctx: The context for the Redis and chain calls.
+// - payload: The signed submission.
+//
+// Returns:
+// - The vote outcome, or a *SubmitError with the reason and HTTP status of the rejection.
+func Submit(ctx context.Context, payload *Payload) (core.VoteOutcome, error) {
+	reservation, ok := pubKeyLimiter.Reserve(payload.PubKey)
+	if !ok {
+		svc.METRICS.RejectedRequests.WithLabelValues(RejectPubKeyRate).Inc()
+		return core.VoteOutcome{}, rejectf(http.StatusTooManyRequests, "too many submissions")
 	}
 
-	if ok, err := svc.MONITOR.VerifyOperator(address); err != nil || !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid operator"})
-		return
+	address, err := verifyPayload(payload)
+	if err != nil {
+		// the token is only spent once the signature proves the operator sent the submission
+		reservation.Cancel()
+		return core.VoteOutcome{}, err
 	}
 
-	verificationKey := fmt.Sprintf("%s%d", core.PkTaskVerification, payload.TaskId)
-	var taskVerification core.TaskVerification
-
-	existingData, err := core.S.RedisConn.Get(c, verificationKey).Result()
-	if err != nil && err != redis.Nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get verification data"})
-		return
+	if banned, err := core.IsBanned(ctx, address); err != nil {
+		return core.VoteOutcome{}, &SubmitError{Status: http.StatusInternalServerError, Err: err}
+	} else if banned {
+		return core.VoteOutcome{}, rejectf(http.StatusForbidden, "operator is banned")
 	}
 
-	if existingData != "" {
-		if err := json.Unmarshal([]byte(existingData), &taskVerification); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse verification data"})
-			return
-		}
-	} else {
-		taskVerification = core.TaskVerification{
-			Attesters: make(map[string]*core.TaskSubmission),
+	// the nonce is only consumed once the signature proves the operator sent it
+	if err := guard.Accept(ctx, address, payload.Nonce); err != nil {
+		if errors.Is(err, replay.ErrNonceReused) {
+			// a concurrent submission of the operator may have used a greater nonce, signing
+			// again with a fresh nonce succeeds
+			return core.VoteOutcome{}, &SubmitError{Status: http.StatusConflict, Err: err}
 		}
+		return core.VoteOutcome{}, &SubmitError{Status: http.StatusInternalServerError, Err: err}
 	}
 
-	submission := &core.TaskSubmission{
-		Address:   address,
-		Result:    payload.Result,
-		Timestamp: payload.Timestamp,
-		Role:      payload.Role,
+	pkTaskFinished := fmt.Sprintf("%s%d", core.PkTaskFinished, payload.TaskId)
+	if isExist, err := core.S.RedisConn.Exists(ctx, pkTaskFinished).Result(); err != nil || isExist == 1 {
+		return core.VoteOutcome{}, &SubmitError{Status: http.StatusBadRequest, Err: core.ErrTaskFinished}
+	}
+
+	if ok, err := svc.MONITOR.VerifyOperator(ctx, address); err != nil {
+		return core.VoteOutcome{}, rejectf(http.StatusInternalServerError, "failed to verify operator")
+	} else if !ok {
+		return core.VoteOutcome{}, rejectf(http.StatusBadRequest, "invalid operator")
 	}
 
 	if payload.Role == core.RolePerformer {
-		if taskVerification.Performer != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "performer already submitted"})
-			return
+		assigned, err := svc.MONITOR.AssignedPerformer(ctx, payload.TaskId)
+		if errors.Is(err, svc.ErrUnknownTask) {
+			return core.VoteOutcome{}, &SubmitError{Status: http.StatusBadRequest, Err: err}
 		}
-		taskVerification.Performer = submission
-	} else {
-		if _, exists := taskVerification.Attesters[address]; exists {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "attester already submitted"})
-			return
+		if err != nil {
+			return core.VoteOutcome{}, rejectf(http.StatusInternalServerError, "failed to get task assignment")
+		}
+		if address != assigned {
+			return core.VoteOutcome{}, rejectf(http.StatusForbidden, "operator is not the assigned performer")
 		}
-		taskVerification.Attesters[address] = submission
 	}
 
-	updatedData, err := json.Marshal(taskVerification)
+	// the type comes from the StateBank, the network the operator signed must only agree with it
+	taskType, err := svc.MONITOR.TaskType(ctx, payload.TaskId)
+	if errors.Is(err, svc.ErrTaskTypeNotIndexed) {
+		return core.VoteOutcome{}, &SubmitError{Status: http.StatusServiceUnavailable, Err: err}
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to marshal verification data"})
-		return
+		core.L.Error(fmt.Sprintf("Failed to get task network, due to {%s}", err))
+		return core.VoteOutcome{}, rejectf(http.StatusInternalServerError, "failed to get task network")
+	}
+	network := payload.Network
+	if network == "" {
+		network = core.DefaultTaskType
 	}
+	if network != taskType {
+		return core.VoteOutcome{}, &SubmitError{Status: http.StatusBadRequest, Err: core.ErrTaskTypeMismatch}
+	}
+	if !core.KnownTaskType(taskType) {
+		return core.VoteOutcome{}, rejectf(http.StatusBadRequest, "task network %s is not configured in [consensus]", taskType)
+	}
+	policy := core.ConsensusPolicyFor(taskType)
 
-	if err := core.S.RedisConn.Set(c, verificationKey, updatedData, 24*time.Hour).Err(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save verification data"})
-		return
+	submission := &core.TaskSubmission{
+		Address:   address,
+		Result:    payload.Result,
+		Timestamp: payload.Timestamp,
+		Role:      payload.Role,
+		TaskType:  taskType,
 	}
 
-	// Only process the task if we have both performer and minimum number of attesters
-	if taskVerification.Performer != nil && len(taskVerification.Attesters) >= core.MinimumAttesters {
-		totalVotes := len(taskVerification.Attesters)
-		positiveVotes := 0
-		negativeVotes := 0
-
-		fmt.Println("\n////////////////////////////////////////////////////////////////")
-		fmt.Println("                         VOTE COLLECTION")
-		fmt.Println("////////////////////////////////////////////////////////////////")
-		fmt.Printf("Processing task %d with %d attesters\n", payload.TaskId, totalVotes)
-		fmt.Printf("Performer address: %s\n", taskVerification.Performer.Address)
-
-		// Count votes
-		for address, attester := range taskVerification.Attesters {
-			if attester.Result == "true" {
-				positiveVotes++
-				fmt.Printf("Attester %s voted: true\n", address)
-			} else {
-				negativeVotes++
-				fmt.Printf("Attester %s voted: false\n", address)
-			}
+	if payload.Role == core.RoleAttester && policy.Quorum == core.QuorumByStake {
+		epoch, err := svc.MONITOR.TaskStakeEpoch(ctx, payload.TaskId)
+		if err != nil {
+			core.L.Error(fmt.Sprintf("Failed to get task stake epoch, due to {%s}", err))
+			return core.VoteOutcome{}, rejectf(http.StatusInternalServerError, "failed to get operator stake")
 		}
-
-		fmt.Println("\n////////////////////////////////////////////////////////////////")
-		fmt.Println("                       CONSENSUS CALCULATION")
-		fmt.Println("////////////////////////////////////////////////////////////////")
-		// Calculate percentage of positive and negative votes
-		positivePercentage := (float64(positiveVotes) / float64(totalVotes)) * 100
-		negativePercentage := (float64(negativeVotes) / float64(totalVotes)) * 100
-
-		fmt.Printf("Vote Summary - Total: %d, Positive: %d (%.2f%%), Negative: %d (%.2f%%)\n",
-			totalVotes, positiveVotes, positivePercentage, negativeVotes, negativePercentage)
-
-		// Determine final result based on consensus
-		var finalResult int64
-		if positivePercentage >= core.ConsensusThreshold {
-			finalResult = 1 // Attesters confirm performer's result is correct
-			fmt.Printf("Consensus reached: APPROVED (%.2f%% >= %d%%)\n", positivePercentage, core.ConsensusThreshold)
-		} else if negativePercentage >= core.ConsensusThreshold {
-			finalResult = 0 // Attesters reject performer's result
-			fmt.Printf("Consensus reached: REJECTED (%.2f%% >= %d%%)\n", negativePercentage, core.ConsensusThreshold)
-		} else {
-			fmt.Printf("No consensus reached yet - Positive: %.2f%%, Negative: %.2f%%, Required: %d%%\n",
-				positivePercentage, negativePercentage, core.ConsensusThreshold)
-			c.JSON(http.StatusOK, gin.H{"status": "success", "message": "waiting for more attestations"})
-			return
+		stake, err := svc.MONITOR.OperatorStake(ctx, address, epoch)
+		if err != nil {
+			core.L.Error(fmt.Sprintf("Failed to get operator stake, due to {%s}", err))
+			return core.VoteOutcome{}, rejectf(http.StatusInternalServerError, "failed to get operator stake")
 		}
+		submission.Stake = stake
+		submission.StakeEpoch = epoch
+	}
 
-		fmt.Println("\n////////////////////////////////////////////////////////////////")
-		fmt.Println("                         TASK PROCESSING")
-		fmt.Println("////////////////////////////////////////////////////////////////")
-		task := core.Task{
-			TaskId: payload.TaskId,
-			TaskResult: core.TaskResult{
-				Operator: taskVerification.Performer.Address,
-				Result:   finalResult,
-			},
+	// the verdict is recorded with the performer's submission and weighs in on the consensus
+	if payload.Role == core.RolePerformer && policy.Verification != core.VerifyOff {
+		submission.Check = svc.VERIFIER.Check(ctx, taskType, payload.Result)
+		if submission.Check.Verdict != core.CheckValid {
+			core.L.Info(fmt.Sprintf("Task %d: performer result %s is %s, due to {%s}", payload.TaskId, payload.Result, submission.Check.Verdict, submission.Check.Error))
 		}
+	}
 
-		taskStr, err := json.Marshal(task)
-		if err != nil {
-			fmt.Printf("Error marshaling task: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	deadline, err := svc.MONITOR.TaskDeadline(ctx, policy)
+	if err != nil {
+		core.L.Error(fmt.Sprintf("Failed to get task deadline, due to {%s}", err))
+		return core.VoteOutcome{}, rejectf(http.StatusInternalServerError, "failed to get task deadline")
+	}
 
-		if _, err := core.S.RedisConn.LPush(c, core.PkTaskQueue, taskStr).Result(); err != nil {
-			fmt.Printf("Error pushing task to queue: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	outcome, err := core.RecordVote(ctx, payload.TaskId, submission, policy, deadline)
+	switch {
+	case errors.Is(err, core.ErrTaskFinished), errors.Is(err, core.ErrPerformerAlreadySubmitted), errors.Is(err, core.ErrAttesterAlreadySubmitted), errors.Is(err, core.ErrTaskTypeMismatch):
+		return core.VoteOutcome{}, &SubmitError{Status: http.StatusBadRequest, Err: err}
+	case err != nil:
+		core.L.Error(fmt.Sprintf("Failed to record vote, due to {%s}", err))
+		return core.VoteOutcome{}, rejectf(http.StatusInternalServerError, "failed to save verification data")
+	}
 
-		if err := core.S.RedisConn.Set(c, pkTaskFinished, "1", 24*time.Hour).Err(); err != nil {
-			fmt.Printf("Error marking task as finished: %v\n", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark task as finished"})
-			return
+	// notify attesters waiting on the task stream
+	if payload.Role == core.RolePerformer {
+		performerStr, _ := json.Marshal(performerData(submission))
+		channel := fmt.Sprintf("%s%d", core.ChTaskPerformer, payload.TaskId)
+		if err := core.S.RedisConn.Publish(ctx, channel, performerStr).Err(); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to publish performer data, due to {%s}", err))
 		}
+	}
 
+	fmt.Printf("Task %d: %s, %d attesters, weight %s of %s voted true\n", payload.TaskId, outcome.Status, outcome.Attesters, outcome.PositiveStake, outcome.TotalStake)
+	if outcome.Status == core.VoteApproved || outcome.Status == core.VoteRejected {
 		fmt.Printf("Task %d successfully processed and queued\n", payload.TaskId)
-		c.JSON(http.StatusOK, gin.H{"status": "success", "message": "task processed"})
 	}
+	return outcome, nil
 }
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// GetDeadLetters lists the tasks whose RespondToTask submission failed too often, newest first.
+//
+// Each entry is the queued task with its attempts and last error. Entries that are not valid
+// JSON are returned as strings.
+func GetDeadLetters(c *gin.Context) {
+	items, err := core.DeadLetters(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	deadLetters := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if json.Valid([]byte(item)) {
+			deadLetters = append(deadLetters, json.RawMessage(item))
+		} else {
+			deadLetters = append(deadLetters, item)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"deadLetters": deadLetters})
+}
+
+// RedriveDeadLetter queues the dead-lettered task with the given id again.
+//
+// The task gets a fresh attempt count. Returns 404 if no dead letter has that task id.
+func RedriveDeadLetter(c *gin.Context) {
+	taskId, err := strconv.ParseUint(c.Param("taskId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+	redriven, err := core.RedriveDeadLetters(c, &taskId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(redriven) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead letter not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"redriven": redriven})
+}
+
+// RedriveDeadLetters queues all dead-lettered tasks again with a fresh attempt count.
+func RedriveDeadLetters(c *gin.Context) {
+	redriven, err := core.RedriveDeadLetters(c, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"redriven": redriven})
+}
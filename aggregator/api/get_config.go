@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// GetConfig reports the consensus policies in effect.
+//
+// The base policy applies to tasks on the default network, "tasks" lists the policies of
+// the networks with overrides, with inherited fields filled in.
+//
+// Parameters:
+// - c: The gin.Context object representing the HTTP request and response.
+//
+// Returns:
+// - None.
+func GetConfig(c *gin.Context) {
+	consensus := core.CurrentConsensus()
+	c.JSON(http.StatusOK, gin.H{
+		"consensus": gin.H{
+			"policy": consensus.ConsensusPolicy,
+			"tasks":  consensus.Tasks,
+		},
+		"clockSkew": core.C.App.ClockSkew,
+	})
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,21 +14,13 @@ import (
 func GetTaskData(c *gin.Context) {
 	taskId := c.Param("taskId")
 
-	verificationKey := fmt.Sprintf("%s%s", core.PkTaskVerification, taskId)
-	var taskVerification core.TaskVerification
-
-	existingData, err := core.S.RedisConn.Get(c, verificationKey).Result()
+	taskVerification, err := loadTaskVerification(c, taskId)
 	if err == redis.Nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "task data not found"})
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get verification data"})
-		return
-	}
-
-	if err := json.Unmarshal([]byte(existingData), &taskVerification); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to parse verification data"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -38,8 +31,34 @@ func GetTaskData(c *gin.Context) {
 	}
 
 	// Return performer's data
-	c.JSON(http.StatusOK, gin.H{
-		"result":  taskVerification.Performer.Result,
-		"address": taskVerification.Performer.Address,
-	})
+	c.JSON(http.StatusOK, performerData(taskVerification.Performer))
+}
+
+// loadTaskVerification reads the verification data of a task from Redis.
+//
+// taskId is the unique identifier of the task.
+// Returns the verification data, redis.Nil if the task is unknown, or an error.
+func loadTaskVerification(ctx context.Context, taskId string) (*core.TaskVerification, error) {
+	verificationKey := fmt.Sprintf("%s%s", core.PkTaskVerification, taskId)
+	existingData, err := core.S.RedisConn.Get(ctx, verificationKey).Result()
+	if err == redis.Nil {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verification data")
+	}
+
+	var taskVerification core.TaskVerification
+	if err := json.Unmarshal([]byte(existingData), &taskVerification); err != nil {
+		return nil, fmt.Errorf("failed to parse verification data")
+	}
+	return &taskVerification, nil
+}
+
+// performerData is the performer submission as exposed to attesters.
+func performerData(performer *core.TaskSubmission) gin.H {
+	return gin.H{
+		"result":  performer.Result,
+		"address": performer.Address,
+	}
 }
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+	"github.com/satlayer/hello-world-bvs/aggregatorpb"
+)
+
+// grpcServer implements the gRPC Aggregator service on top of the same logic as the REST API.
+type grpcServer struct {
+	aggregatorpb.UnimplementedAggregatorServer
+}
+
+// RegisterGRPC registers the Aggregator service on a gRPC server.
+//
+// server is the gRPC server the service is served by.
+// No return values.
+func RegisterGRPC(server *grpc.Server) {
+	aggregatorpb.RegisterAggregatorServer(server, &grpcServer{})
+}
+
+// GRPCOptions returns the options the gRPC server must be created with to apply [limits]:
+// messages are limited to bodyLimit bytes, and calls per client IP to ipRate.
+func GRPCOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(int(core.C.Limits.BodyLimit)),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := limitPeer(ctx); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := limitPeer(stream.Context()); err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+}
+
+// limitPeer takes a token from the rate limit of the calling client IP.
+func limitPeer(ctx context.Context) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		host = p.Addr.String()
+	}
+	if !ipLimiter.Allow(host) {
+		svc.METRICS.RejectedRequests.WithLabelValues(RejectIpRate).Inc()
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	return nil
+}
+
+// SubmitResult verifies a signed submission and records it as a vote, see Submit.
+//
+// Rejected submissions are reported with the gRPC code of their HTTP status, except for
+// duplicates and finished tasks, which are ALREADY_EXISTS.
+func (s *grpcServer) SubmitResult(ctx context.Context, req *aggregatorpb.SubmitResultRequest) (*aggregatorpb.SubmitResultResponse, error) {
+	outcome, err := Submit(ctx, &Payload{
+		Version:   int(req.Version),
+		TaskId:    req.TaskId,
+		Result:    req.Result,
+		Timestamp: req.Timestamp,
+		Nonce:     req.Nonce,
+		Signature: req.Signature,
+		PubKey:    req.PubKey,
+		Role:      req.Role,
+		Network:   req.Network,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &aggregatorpb.SubmitResultResponse{Status: outcome.Status, Message: SubmitMessage(outcome.Status)}, nil
+}
+
+// GetPerformerData returns the performer's submission of a task, NOT_FOUND until it submitted.
+func (s *grpcServer) GetPerformerData(ctx context.Context, req *aggregatorpb.GetPerformerDataRequest) (*aggregatorpb.PerformerData, error) {
+	taskVerification, err := loadTaskVerification(ctx, strconv.FormatUint(req.TaskId, 10))
+	if err != nil && err != redis.Nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if taskVerification == nil || taskVerification.Performer == nil {
+		return nil, status.Error(codes.NotFound, "performer data not found")
+	}
+	return &aggregatorpb.PerformerData{Result: taskVerification.Performer.Result, Address: taskVerification.Performer.Address}, nil
+}
+
+// StreamTaskEvents sends a "performer" event as soon as the performer of a task submitted.
+//
+// Like the Server-Sent Events stream, it sends a "timeout" event instead if the performer does
+// not submit within streamTimeout or the client's deadline, whichever is first, and ends the
+// stream after either event.
+func (s *grpcServer) StreamTaskEvents(req *aggregatorpb.StreamTaskEventsRequest, stream aggregatorpb.Aggregator_StreamTaskEventsServer) error {
+	ctx, cancel := context.WithTimeout(stream.Context(), streamTimeout)
+	defer cancel()
+
+	// subscribe before reading the current state so a submission in between is not missed
+	pubsub := core.S.RedisConn.Subscribe(ctx, fmt.Sprintf("%s%d", core.ChTaskPerformer, req.TaskId))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return status.Error(codes.Unavailable, "failed to subscribe to task")
+	}
+
+	performer, err := s.GetPerformerData(ctx, &aggregatorpb.GetPerformerDataRequest{TaskId: req.TaskId})
+	if err == nil {
+		return stream.Send(&aggregatorpb.TaskEvent{Type: "performer", Performer: performer})
+	}
+	if status.Code(err) != codes.NotFound {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		if stream.Context().Err() != nil {
+			return stream.Context().Err()
+		}
+		return stream.Send(&aggregatorpb.TaskEvent{Type: "timeout"})
+	case msg, ok := <-pubsub.Channel():
+		if !ok {
+			return status.Error(codes.Unavailable, "task subscription closed")
+		}
+		performer := &aggregatorpb.PerformerData{}
+		if err := json.Unmarshal([]byte(msg.Payload), performer); err != nil {
+			return status.Error(codes.Internal, "failed to parse performer data")
+		}
+		return stream.Send(&aggregatorpb.TaskEvent{Type: "performer", Performer: performer})
+	}
+}
+
+// grpcError converts an error of Submit to a gRPC status.
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, core.ErrTaskFinished), errors.Is(err, core.ErrPerformerAlreadySubmitted), errors.Is(err, core.ErrAttesterAlreadySubmitted):
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	var submitErr *SubmitError
+	if !errors.As(err, &submitErr) {
+		return status.Error(codes.Internal, err.Error())
+	}
+	switch submitErr.Status {
+	case http.StatusBadRequest:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case http.StatusForbidden:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case http.StatusConflict:
+		return status.Error(codes.Aborted, err.Error())
+	case http.StatusTooManyRequests:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case http.StatusServiceUnavailable:
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
+
+// GetLeader returns the replica holding the leader lease, and whether it is this replica.
+//
+// The leader is null while no replica holds the lease.
+func GetLeader(c *gin.Context) {
+	leader, err := core.CurrentLeader(c)
+	if err != nil && err != redis.Nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"instance": svc.InstanceId,
+		"leader":   leader,
+		"isLeader": leader != nil && leader.Holder == svc.InstanceId,
+	})
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/ratelimit"
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
+
+// Reasons requests are rejected for, the label of svc.METRICS.RejectedRequests
+const (
+	RejectBodyTooLarge = "body_too_large"
+	RejectIpRate       = "ip_rate"
+	RejectPubKeyRate   = "pubkey_rate"
+)
+
+var (
+	// ipLimiter limits the requests per client IP, pubKeyLimiter the submissions per public key
+	ipLimiter     = ratelimit.New(core.C.Limits.IpRate, core.C.Limits.IpBurst)
+	pubKeyLimiter = ratelimit.New(core.C.Limits.PubKeyRate, core.C.Limits.PubKeyBurst)
+)
+
+// LimitRequests rejects requests from client IPs that exceed [limits] ipRate with 429, and
+// requests with a body larger than [limits] bodyLimit with 413.
+//
+// Bodies without a Content-Length are cut off at the limit while they are read.
+func LimitRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !ipLimiter.Allow(c.ClientIP()) {
+			reject(c, http.StatusTooManyRequests, RejectIpRate, "too many requests")
+			return
+		}
+		if c.Request.ContentLength > core.C.Limits.BodyLimit {
+			reject(c, http.StatusRequestEntityTooLarge, RejectBodyTooLarge, "request body too large")
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, core.C.Limits.BodyLimit)
+		c.Next()
+	}
+}
+
+// reject aborts a request and counts it as rejected for reason.
+func reject(c *gin.Context, status int, reason string, message string) {
+	svc.METRICS.RejectedRequests.WithLabelValues(reason).Inc()
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// isBodyTooLarge reports whether err comes from reading a body beyond the limit.
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
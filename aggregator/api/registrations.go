@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
+
+// RefreshRegistrations refreshes the cached registration of every operator that ever submitted.
+//
+// Returns the number of refreshed operators and how many of them are registered.
+func RefreshRegistrations(c *gin.Context) {
+	refreshed, registered, err := svc.MONITOR.RefreshOperators(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"refreshed": refreshed, "registered": registered})
+}
+
+// RefreshRegistration refreshes the cached registration of one operator.
+//
+// Returns whether the operator is registered, or 502 if the BVS directory could not be queried.
+func RefreshRegistration(c *gin.Context) {
+	address := c.Param("address")
+	registered, err := svc.MONITOR.RefreshOperator(c, address)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address, "registered": registered})
+}
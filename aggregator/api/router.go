@@ -1,13 +1,43 @@
 package api
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
 
 // SetupRoutes sets up routes for the aggregator API.
 //
+// Every route is subject to the [limits] on request size and client IP rate. The admin
+// routes, the dead letters and the other routes that change state require the [admin] token
+// and are audited.
 // router is the Gin Engine instance used to set up the routes.
 // No return values.
 func SetupRoutes(router *gin.Engine) {
+	router.Use(LimitRequests())
 	router.POST("api/aggregator", Aggregator)
 	router.GET("api/aggregator/task/:taskId", GetTaskData)
+	router.GET("api/aggregator/task/:taskId/stream", StreamTaskData)
+	router.GET("api/aggregator/config", GetConfig)
+	router.GET("api/aggregator/leader", GetLeader)
+	router.GET("api/aggregator/tasks", ListTasks)
+	router.GET("api/aggregator/tasks/:taskId", GetTask)
+	router.GET("api/aggregator/operators/:address/history", GetOperatorHistory)
+	router.POST("api/aggregator/operators/registrations/refresh", AuditRequests(), AdminAuth(), RefreshRegistrations)
+	router.POST("api/aggregator/operators/:address/registration/refresh", AuditRequests(), AdminAuth(), RefreshRegistration)
+	router.GET("api/aggregator/dead-letters", AuditRequests(), AdminAuth(), GetDeadLetters)
+	router.POST("api/aggregator/dead-letters/redrive", AuditRequests(), AdminAuth(), RedriveDeadLetters)
+	router.POST("api/aggregator/dead-letters/:taskId/redrive", AuditRequests(), AdminAuth(), RedriveDeadLetter)
 
+	admin := router.Group("api/aggregator/admin", AuditRequests(), AdminAuth())
+	admin.GET("tasks/:taskId", GetTaskState)
+	admin.POST("tasks/:taskId/resolve", ResolveTask)
+	admin.POST("tasks/:taskId/requeue", RequeueTask)
+	admin.DELETE("tasks/:taskId", PurgeTask)
+	admin.GET("operators/banned", GetBannedOperators)
+	admin.PUT("operators/:address/ban", BanOperator)
+	admin.DELETE("operators/:address/ban", UnbanOperator)
+	admin.GET("audit", GetAuditLog)
+	router.GET("metrics", gin.WrapH(promhttp.HandlerFor(svc.Registry, promhttp.HandlerOpts{})))
 }
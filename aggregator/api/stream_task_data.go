@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+const (
+	// streamTimeout is how long a subscriber waits for the performer before the stream is closed
+	streamTimeout = 60 * time.Second
+	// streamKeepAlive is the interval of comment lines that keep idle proxies from closing the stream
+	streamKeepAlive = 15 * time.Second
+)
+
+// StreamTaskData streams the performer submission of a task as Server-Sent Events.
+//
+// If the performer already submitted, a single "performer" event is sent right away.
+// Otherwise the handler waits for the submission to be published and sends it as a
+// "performer" event, or sends a "timeout" event after streamTimeout. The stream is
+// closed after either event.
+//
+// Parameters:
+// - c: The gin.Context object representing the HTTP request and response.
+//
+// Returns:
+// - None.
+func StreamTaskData(c *gin.Context) {
+	taskId := c.Param("taskId")
+	ctx, cancel := context.WithTimeout(c.Request.Context(), streamTimeout)
+	defer cancel()
+
+	// subscribe before reading the current state so a submission in between is not missed
+	pubsub := core.S.RedisConn.Subscribe(ctx, fmt.Sprintf("%s%s", core.ChTaskPerformer, taskId))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to subscribe to task"})
+		return
+	}
+
+	taskVerification, err := loadTaskVerification(ctx, taskId)
+	if err != nil && err != redis.Nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	if taskVerification != nil && taskVerification.Performer != nil {
+		c.SSEvent("performer", performerData(taskVerification.Performer))
+		c.Writer.Flush()
+		return
+	}
+	c.Writer.Flush()
+
+	messages := pubsub.Channel()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if c.Request.Context().Err() == nil {
+				c.SSEvent("timeout", gin.H{"error": "performer data not found"})
+				c.Writer.Flush()
+			}
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			// the payload is the JSON encoded performer data, forward it as is
+			c.SSEvent("performer", msg.Payload)
+			c.Writer.Flush()
+			return
+		}
+	}
+}
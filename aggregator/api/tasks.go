@@ -0,0 +1,104 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// ListTasks lists the persisted tasks, newest first.
+//
+// Query parameters:
+// - status: only tasks with this status ("recorded", "pending", "approved", "rejected" or "expired")
+// - offset, limit: the page, limit defaults to 20 and is at most 100
+func ListTasks(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !validStatus(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status", "statuses": core.TaskStatuses})
+		return
+	}
+	offset, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	tasks, total, err := core.ListTasks(c, status, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tasks": tasks, "total": total, "offset": offset, "limit": limit})
+}
+
+// GetTask returns the persisted history of a task: every vote with its timestamp, the
+// deadline and the final result.
+func GetTask(c *gin.Context) {
+	taskId, err := strconv.ParseUint(c.Param("taskId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task id"})
+		return
+	}
+	task, err := core.LoadTask(c, taskId)
+	if err == redis.Nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// GetOperatorHistory returns an operator's participation counters, its agreement rate and a
+// page of the finished tasks it performed, attested or missed, newest first.
+//
+// Query parameters:
+// - offset, limit: the page, limit defaults to 20 and is at most 100
+func GetOperatorHistory(c *gin.Context) {
+	address := c.Param("address")
+	offset, limit, ok := pagination(c)
+	if !ok {
+		return
+	}
+
+	stats, tasks, total, err := core.OperatorHistory(c, address, offset, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address, "stats": stats, "tasks": tasks, "total": total, "offset": offset, "limit": limit})
+}
+
+// pagination parses the offset and limit query parameters, replying 400 if they are invalid.
+func pagination(c *gin.Context) (int64, int64, bool) {
+	offset, err := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return 0, 0, false
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", strconv.Itoa(defaultPageSize)), 10, 64)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return 0, 0, false
+	}
+	return offset, limit, true
+}
+
+func validStatus(status string) bool {
+	for _, s := range core.TaskStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
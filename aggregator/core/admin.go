@@ -0,0 +1,350 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrTaskNotFound is returned when no state of a task is stored.
+	ErrTaskNotFound = errors.New("task not found")
+	// ErrNoOperator is returned when a task is resolved without an operator and has no performer.
+	ErrNoOperator = errors.New("no operator given and the performer never submitted")
+)
+
+var resolveTask = redis.NewScript(ResolveTaskScript)
+
+// TaskState is everything stored about a task, as kept in Redis.
+type TaskState struct {
+	TaskId       uint64          `json:"taskId"`
+	Verification json.RawMessage `json:"verification"`
+	History      json.RawMessage `json:"history"`
+	Finished     bool            `json:"finished"`
+	// FinishedTTL is the remaining lifetime of the finished flag and the verification data, in seconds
+	FinishedTTL    int64    `json:"finishedTtl"`
+	Assignment     string   `json:"assignment"`
+	Deadline       *float64 `json:"deadline"`
+	DeadlineHeight *float64 `json:"deadlineHeight"`
+	// Deliveries are the raw queue items of the task, by the list or set they are in
+	Deliveries map[string][]string `json:"deliveries"`
+}
+
+// LoadTaskState reads everything stored about a task.
+//
+// ctx is the context for the Redis calls.
+// taskId is the unique identifier of the task.
+// Returns the state, ErrTaskNotFound if nothing is stored, or an error if Redis fails.
+func LoadTaskState(ctx context.Context, taskId uint64) (*TaskState, error) {
+	state := &TaskState{TaskId: taskId, Deliveries: map[string][]string{}}
+	member := fmt.Sprint(taskId)
+	var (
+		verification, history, assignment *redis.StringCmd
+		finishedTTL                       *redis.DurationCmd
+		deadline, deadlineHeight          *redis.FloatCmd
+	)
+	_, err := S.RedisConn.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		verification = pipe.Get(ctx, fmt.Sprintf("%s%d", PkTaskVerification, taskId))
+		history = pipe.Get(ctx, PkTaskHistory+member)
+		assignment = pipe.Get(ctx, fmt.Sprintf("%s%d", PkTaskAssignment, taskId))
+		finishedTTL = pipe.TTL(ctx, fmt.Sprintf("%s%d", PkTaskFinished, taskId))
+		deadline = pipe.ZScore(ctx, PkTaskDeadlines, member)
+		deadlineHeight = pipe.ZScore(ctx, PkTaskDeadlineHeights, member)
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read task state: %v", err)
+	}
+	if v, err := verification.Result(); err == nil {
+		state.Verification = json.RawMessage(v)
+	}
+	if v, err := history.Result(); err == nil {
+		state.History = json.RawMessage(v)
+	}
+	state.Assignment = assignment.Val()
+	// TTL is -2 if the flag does not exist and -1 if it never expires
+	if ttl := finishedTTL.Val(); ttl != -2 {
+		state.Finished = true
+		state.FinishedTTL = int64(ttl.Seconds())
+	}
+	if v, err := deadline.Result(); err == nil {
+		state.Deadline = &v
+	}
+	if v, err := deadlineHeight.Result(); err == nil {
+		state.DeadlineHeight = &v
+	}
+
+	deliveries, err := taskDeliveries(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	state.Deliveries = deliveries
+
+	if state.Verification == nil && state.History == nil && !state.Finished && state.Assignment == "" &&
+		state.Deadline == nil && state.DeadlineHeight == nil && len(state.Deliveries) == 0 {
+		return nil, ErrTaskNotFound
+	}
+	return state, nil
+}
+
+// taskDeliveries returns the raw queue items of a task, by the list or set they are in.
+func taskDeliveries(ctx context.Context, taskId uint64) (map[string][]string, error) {
+	deliveries := map[string][]string{}
+	add := func(key string, items []string) {
+		for _, item := range items {
+			var task Task
+			if json.Unmarshal([]byte(item), &task) == nil && task.TaskId == taskId {
+				deliveries[key] = append(deliveries[key], item)
+			}
+		}
+	}
+	for _, key := range []string{PkTaskQueue, PkTaskProcessing, PkTaskDeadLetter} {
+		items, err := S.RedisConn.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", key, err)
+		}
+		add(key, items)
+	}
+	items, err := S.RedisConn.ZRange(ctx, PkTaskRetry, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", PkTaskRetry, err)
+	}
+	add(PkTaskRetry, items)
+	return deliveries, nil
+}
+
+// ResolveTask finalizes a task with a result chosen by an administrator and queues it for
+// RespondToTask, see ResolveTaskScript.
+//
+// ctx is the context for the Redis call.
+// taskId is the unique identifier of the task.
+// result is the result code to submit.
+// operator is the operator to submit the result for, "" for the task's performer.
+// actor and reason are recorded with the resolution.
+// Returns the operator the result is submitted for, ErrTaskFinished if the task is already
+// finished, ErrNoOperator if no operator is known, or an error if Redis fails.
+func ResolveTask(ctx context.Context, taskId uint64, result int64, operator string, actor string, reason string) (string, error) {
+	keys := []string{
+		fmt.Sprintf("%s%d", PkTaskVerification, taskId),
+		fmt.Sprintf("%s%d", PkTaskFinished, taskId),
+		PkTaskQueue,
+		PkTaskDeadlines,
+		PkTaskDeadlineHeights,
+	}
+	keys = append(keys, historyKeys(taskId)...)
+	res, err := resolveTask.Run(ctx, S.RedisConn, keys,
+		taskId, result, operator, int64(taskTTL.Seconds()), actor, reason, time.Now().Unix(),
+	).Slice()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve task: %v", err)
+	}
+	if len(res) != 2 {
+		return "", fmt.Errorf("unexpected resolve script result: %v", res)
+	}
+	switch res[0] {
+	case "finished":
+		return "", ErrTaskFinished
+	case "no_operator":
+		return "", ErrNoOperator
+	}
+	operator, _ = res[1].(string)
+	return operator, nil
+}
+
+// PurgeTask deletes everything stored about a task: its verification data, finished flag,
+// history, assignment, deadlines and every pending delivery.
+//
+// Operator statistics the task already counted towards are kept. A task purged while it is
+// being submitted is still submitted.
+// ctx is the context for the Redis calls.
+// taskId is the unique identifier of the task.
+// Returns the number of keys and items removed, or an error if Redis fails.
+func PurgeTask(ctx context.Context, taskId uint64) (int64, error) {
+	member := fmt.Sprint(taskId)
+	deliveries, err := taskDeliveries(ctx, taskId)
+	if err != nil {
+		return 0, err
+	}
+	cmds, err := S.RedisConn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx,
+			fmt.Sprintf("%s%d", PkTaskVerification, taskId),
+			fmt.Sprintf("%s%d", PkTaskFinished, taskId),
+			fmt.Sprintf("%s%d", PkTaskAssignment, taskId),
+			fmt.Sprintf("%s%d", PkTaskStakeEpoch, taskId),
+			fmt.Sprintf("%s%d", PkTaskNetwork, taskId),
+			PkTaskHistory+member,
+		)
+		pipe.ZRem(ctx, PkTaskDeadlines, member)
+		pipe.ZRem(ctx, PkTaskDeadlineHeights, member)
+		pipe.ZRem(ctx, PkTaskIndex, member)
+		pipe.ZRem(ctx, PkTaskUnconfirmed, member)
+		for _, status := range TaskStatuses {
+			pipe.ZRem(ctx, PkTaskIndex+":"+status, member)
+		}
+		for key, items := range deliveries {
+			for _, item := range items {
+				if key == PkTaskRetry {
+					pipe.ZRem(ctx, key, item)
+				} else {
+					pipe.LRem(ctx, key, 0, item)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge task: %v", err)
+	}
+	var removed int64
+	for _, cmd := range cmds {
+		removed += cmd.(*redis.IntCmd).Val()
+	}
+	return removed, nil
+}
+
+// BanOperator rejects all further submissions of an operator.
+//
+// ctx is the context for the Redis call.
+// address is the address of the operator.
+// Returns whether the operator was not banned before, or an error if Redis fails.
+func BanOperator(ctx context.Context, address string) (bool, error) {
+	n, err := S.RedisConn.SAdd(ctx, PkBannedOperators, address).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to ban operator: %v", err)
+	}
+	return n == 1, nil
+}
+
+// UnbanOperator accepts submissions of a banned operator again.
+//
+// ctx is the context for the Redis call.
+// address is the address of the operator.
+// Returns whether the operator was banned, or an error if Redis fails.
+func UnbanOperator(ctx context.Context, address string) (bool, error) {
+	n, err := S.RedisConn.SRem(ctx, PkBannedOperators, address).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to unban operator: %v", err)
+	}
+	return n == 1, nil
+}
+
+// IsBanned reports whether an operator is banned.
+//
+// ctx is the context for the Redis call.
+// address is the address of the operator.
+// Returns whether the operator is banned, or an error if Redis fails.
+func IsBanned(ctx context.Context, address string) (bool, error) {
+	banned, err := S.RedisConn.SIsMember(ctx, PkBannedOperators, address).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to read banned operators: %v", err)
+	}
+	return banned, nil
+}
+
+// BannedOperators returns the banned operators.
+//
+// ctx is the context for the Redis call.
+// Returns the addresses, or an error if Redis fails.
+func BannedOperators(ctx context.Context) ([]string, error) {
+	addresses, err := S.RedisConn.SMembers(ctx, PkBannedOperators).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read banned operators: %v", err)
+	}
+	return addresses, nil
+}
+
+// AuditEntry is a request to the admin API.
+//
+// ReportedActor is the X-Admin-Actor header. Every holder of the shared token can set it to any
+// name, so it tells who claims to have made the request, not who did.
+type AuditEntry struct {
+	Time          int64             `json:"time"`
+	ReportedActor string            `json:"reportedActor"`
+	Ip            string            `json:"ip"`
+	Method        string            `json:"method"`
+	Path          string            `json:"path"`
+	Params        map[string]string `json:"params,omitempty"`
+	Body          string            `json:"body,omitempty"`
+	Status        int               `json:"status"`
+	Error         string            `json:"error,omitempty"`
+}
+
+// Audit appends an entry of an authenticated request to the audit log, dropping the oldest
+// entries beyond AuditLogSize.
+//
+// The entry is also written to the log.
+// ctx is the context for the Redis calls.
+// entry is the request to record.
+// Returns an error if Redis fails.
+func Audit(ctx context.Context, entry AuditEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+	L.Info(fmt.Sprintf("Admin audit: {%s}", b))
+	return appendAuditLog(ctx, PkAdminAudit, b, AuditLogSize)
+}
+
+// AuditRejected appends an entry of a request without a valid token to the log of rejected
+// requests, dropping the oldest entries beyond AuditRejectedLogSize.
+//
+// Rejected requests are kept apart from the audit log, so that anyone who can reach the API
+// cannot push the authenticated requests out of it. The entry is also written to the log.
+// ctx is the context for the Redis calls.
+// entry is the request to record.
+// Returns an error if Redis fails.
+func AuditRejected(ctx context.Context, entry AuditEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %v", err)
+	}
+	L.Info(fmt.Sprintf("Admin audit, rejected: {%s}", b))
+	return appendAuditLog(ctx, PkAdminAuditRejected, b, AuditRejectedLogSize)
+}
+
+// appendAuditLog pushes an entry to the list key and trims it to the newest size entries.
+func appendAuditLog(ctx context.Context, key string, entry []byte, size int64) error {
+	_, err := S.RedisConn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LPush(ctx, key, entry)
+		pipe.LTrim(ctx, key, 0, size-1)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return nil
+}
+
+// AuditLog returns a page of the audit log, newest first.
+//
+// ctx is the context for the Redis calls.
+// rejected selects the log of requests without a valid token instead.
+// offset and limit select the page.
+// Returns the entries and the total number of entries, or an error if Redis fails.
+func AuditLog(ctx context.Context, rejected bool, offset int64, limit int64) ([]AuditEntry, int64, error) {
+	key := PkAdminAudit
+	if rejected {
+		key = PkAdminAuditRejected
+	}
+	total, err := S.RedisConn.LLen(ctx, key).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	items, err := S.RedisConn.LRange(ctx, key, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read audit log: %v", err)
+	}
+	entries := make([]AuditEntry, 0, len(items))
+	for _, item := range items {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, nil
+}
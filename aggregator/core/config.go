@@ -14,6 +14,9 @@ var C Config
 var L logger.Logger
 var S Store
 
+// envFilePath is the env.toml the configuration is loaded from.
+var envFilePath string
+
 // init Initializes the package by loading configuration from env.toml and setting up the logger.
 //
 // No parameters.
@@ -26,12 +29,42 @@ func init() {
 
 	// get env.file path
 	configDir := filepath.Dir(currentFile)
-	envFilePath := filepath.Join(configDir, "../env.toml")
+	envFilePath = filepath.Join(configDir, "../env.toml")
 	if _, err := toml.DecodeFile(envFilePath, &C); err != nil {
 		panic(err)
 	}
+	if C.Limits.BodyLimit <= 0 {
+		C.Limits.BodyLimit = DefaultBodyLimit
+	}
+	if err := setConsensus(C.Consensus); err != nil {
+		panic(fmt.Sprintf("invalid consensus policy: %v", err))
+	}
+	if err := checkStake(C.Stake); err != nil {
+		panic(fmt.Sprintf("invalid stake configuration: %v", err))
+	}
 	fmt.Printf("C: %+v", C)
 	// init logger
 	L = logger.NewELKLogger(C.Chain.BvsHash)
 	initStore(&C.Database)
 }
+
+// checkStake validates the [stake] section.
+//
+// stake is the section as loaded from env.toml.
+// Returns an error if a strategy is listed twice or has more than StakeDecimals decimals.
+func checkStake(stake Stake) error {
+	seen := make(map[string]bool, len(stake.Strategies))
+	for _, strategy := range stake.Strategies {
+		if strategy.Address == "" {
+			return fmt.Errorf("strategy without address")
+		}
+		if seen[strategy.Address] {
+			return fmt.Errorf("strategy %s is listed twice", strategy.Address)
+		}
+		seen[strategy.Address] = true
+		if strategy.Decimals < 0 || strategy.Decimals > StakeDecimals {
+			return fmt.Errorf("decimals of strategy %s must be between 0 and %d", strategy.Address, StakeDecimals)
+		}
+	}
+	return nil
+}
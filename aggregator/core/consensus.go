@@ -0,0 +1,208 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultTaskType is the type of tasks that target the operators' default network.
+const DefaultTaskType = "default"
+
+const (
+	QuorumByStake = "stake" // votes are weighted by the attesters' delegated stake
+	QuorumByCount = "count" // every attester has one vote
+
+	TiePending = "pending" // keep waiting for more votes
+	TieApprove = "approve"
+	TieReject  = "reject"
+
+	VerifyOff      = "off"      // the aggregator does not check performer results
+	VerifyTieBreak = "tiebreak" // the aggregator's verdict decides evenly split votes
+	VerifyVeto     = "veto"     // an invalid verdict turns an approval into a rejection
+
+	CheckValid   = "valid"
+	CheckInvalid = "invalid"
+	CheckError   = "error" // the trusted RPCs could not be read or did not agree
+)
+
+// ConsensusPolicy is the rule set the votes of a task are evaluated with.
+type ConsensusPolicy struct {
+	Threshold        int    `json:"threshold"`        // percentage of the votes required, above 50
+	MinimumAttesters int    `json:"minimumAttesters"` // attesters required before consensus is evaluated
+	MinimumStake     string `json:"minimumStake"`     // total voting stake required in stake quorum, as a decimal string
+	Quorum           string `json:"quorum"`           // "stake" or "count"
+	Tie              string `json:"tie"`              // "pending", "approve" or "reject" when the votes are split evenly
+	Timeout          int    `json:"timeout"`          // seconds after the first submission the task is finalized without consensus
+	TimeoutBlocks    int64  `json:"timeoutBlocks"`    // blocks after the first submission the task is finalized, 0 for none
+	Verification     string `json:"verification"`     // "off", "tiebreak" or "veto", see [verifier]
+}
+
+// Consensus is the [consensus] section: a base policy and per-task-type overrides.
+//
+// The task type is the network a task targets. Zero fields of an override inherit the base policy.
+type Consensus struct {
+	ConsensusPolicy
+	Tasks map[string]ConsensusPolicy `json:"tasks"`
+}
+
+// DefaultConsensusPolicy is the policy of an empty [consensus] section.
+var DefaultConsensusPolicy = ConsensusPolicy{
+	Threshold:        ConsensusThreshold,
+	MinimumAttesters: MinimumAttesters,
+	MinimumStake:     MinimumStake,
+	Quorum:           QuorumByStake,
+	Tie:              TiePending,
+	Timeout:          int(DefaultTaskTimeout.Seconds()),
+	Verification:     VerifyOff,
+}
+
+var consensus atomic.Pointer[Consensus]
+
+// CurrentConsensus returns the consensus configuration in effect.
+func CurrentConsensus() *Consensus {
+	return consensus.Load()
+}
+
+// ConsensusPolicyFor returns the policy for tasks of the given type.
+func ConsensusPolicyFor(taskType string) ConsensusPolicy {
+	c := consensus.Load()
+	if override, ok := c.Tasks[taskType]; ok {
+		return override
+	}
+	return c.ConsensusPolicy
+}
+
+// KnownTaskType reports whether [consensus] has a policy for tasks of the given type, the
+// default type always has the base policy.
+func KnownTaskType(taskType string) bool {
+	if taskType == DefaultTaskType {
+		return true
+	}
+	_, ok := consensus.Load().Tasks[taskType]
+	return ok
+}
+
+// setConsensus validates c, fills in defaults and makes it the configuration in effect.
+func setConsensus(c Consensus) error {
+	c.ConsensusPolicy = c.ConsensusPolicy.inherit(DefaultConsensusPolicy)
+	if err := c.ConsensusPolicy.validate(); err != nil {
+		return err
+	}
+	tasks := make(map[string]ConsensusPolicy, len(c.Tasks))
+	for taskType, override := range c.Tasks {
+		policy := override.inherit(c.ConsensusPolicy)
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("task type %s: %v", taskType, err)
+		}
+		tasks[taskType] = policy
+	}
+	c.Tasks = tasks
+	consensus.Store(&c)
+	return nil
+}
+
+// inherit returns p with its zero fields taken from base.
+func (p ConsensusPolicy) inherit(base ConsensusPolicy) ConsensusPolicy {
+	if p.Threshold == 0 {
+		p.Threshold = base.Threshold
+	}
+	if p.MinimumAttesters == 0 {
+		p.MinimumAttesters = base.MinimumAttesters
+	}
+	if p.MinimumStake == "" {
+		p.MinimumStake = base.MinimumStake
+	}
+	if p.Quorum == "" {
+		p.Quorum = base.Quorum
+	}
+	if p.Tie == "" {
+		p.Tie = base.Tie
+	}
+	if p.Timeout == 0 {
+		p.Timeout = base.Timeout
+	}
+	if p.TimeoutBlocks == 0 {
+		p.TimeoutBlocks = base.TimeoutBlocks
+	}
+	if p.Verification == "" {
+		p.Verification = base.Verification
+	}
+	return p
+}
+
+func (p ConsensusPolicy) validate() error {
+	if p.Threshold <= 50 || p.Threshold > 100 {
+		return fmt.Errorf("threshold %d must be above 50 and at most 100", p.Threshold)
+	}
+	if p.MinimumAttesters < 1 {
+		return fmt.Errorf("minimumAttesters %d must be at least 1", p.MinimumAttesters)
+	}
+	if stake, ok := new(big.Int).SetString(p.MinimumStake, 10); !ok || stake.Sign() < 0 {
+		return fmt.Errorf("minimumStake %q must be a non-negative integer", p.MinimumStake)
+	}
+	if p.Quorum != QuorumByStake && p.Quorum != QuorumByCount {
+		return fmt.Errorf("quorum %q must be %q or %q", p.Quorum, QuorumByStake, QuorumByCount)
+	}
+	if p.Tie != TiePending && p.Tie != TieApprove && p.Tie != TieReject {
+		return fmt.Errorf("tie %q must be %q, %q or %q", p.Tie, TiePending, TieApprove, TieReject)
+	}
+	// tasks are only kept for 24 hours
+	if p.Timeout < 1 || p.Timeout > 24*60*60 {
+		return fmt.Errorf("timeout %d must be between 1 second and 24 hours", p.Timeout)
+	}
+	if p.TimeoutBlocks < 0 {
+		return fmt.Errorf("timeoutBlocks %d must not be negative", p.TimeoutBlocks)
+	}
+	if p.Verification != VerifyOff && p.Verification != VerifyTieBreak && p.Verification != VerifyVeto {
+		return fmt.Errorf("verification %q must be %q, %q or %q", p.Verification, VerifyOff, VerifyTieBreak, VerifyVeto)
+	}
+	return nil
+}
+
+// ReloadConsensus re-reads the [consensus] section of the env file.
+//
+// The configuration in effect is only replaced if the new one is valid.
+// Returns an error if the file cannot be read or the section is invalid.
+func ReloadConsensus() error {
+	var cfg Config
+	if _, err := toml.DecodeFile(envFilePath, &cfg); err != nil {
+		return fmt.Errorf("failed to read %s: %v", envFilePath, err)
+	}
+	return setConsensus(cfg.Consensus)
+}
+
+// WatchConsensus reloads the [consensus] section whenever the env file changes, until ctx is done.
+//
+// ctx is the context for the watcher.
+// interval is how often the file is checked for changes.
+func WatchConsensus(ctx context.Context, interval time.Duration) {
+	var modTime time.Time
+	if info, err := os.Stat(envFilePath); err == nil {
+		modTime = info.ModTime()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		info, err := os.Stat(envFilePath)
+		if err != nil || !info.ModTime().After(modTime) {
+			continue
+		}
+		modTime = info.ModTime()
+		if err := ReloadConsensus(); err != nil {
+			L.Error(fmt.Sprintf("Failed to reload consensus policy, keeping the current one, due to {%s}", err))
+			continue
+		}
+		L.Info(fmt.Sprintf("Reloaded consensus policy: %+v", *CurrentConsensus()))
+	}
+}
@@ -1,20 +1,511 @@
 package core
 
+import "time"
+
+// archiveTaskLua defines the Lua function that persists the task history, which has no TTL.
+//
+// archive_task(history_key, index_key, task_id, verification_json, old_status, status) stores
+// the task's verification data under task_history:<id> and moves the task between the
+// task_index:<status> sets. Every script that archives declares the history key, task_index and
+// the index of every status in KEYS, see historyKeys, so it only touches keys it declares.
+const archiveTaskLua = `
+	local function archive_task(history_key, index_key, task_id, verification_json, old_status, status)
+		redis.call("SET", history_key, verification_json);
+		redis.call("ZADD", index_key, task_id, task_id);
+		if old_status and old_status ~= status then
+			redis.call("ZREM", index_key .. ":" .. old_status, task_id);
+		end
+		redis.call("ZADD", index_key .. ":" .. status, task_id, task_id);
+	end
+`
+
+// decimalLua defines Lua functions for stakes, which are non-negative decimal strings of any
+// length. Lua numbers are doubles and lose precision above 2^53, so stakes are never converted.
+//
+// dec_norm(a) strips leading zeros, and returns "0" for anything that is not a decimal string.
+// dec_cmp(a, b) returns -1, 0 or 1; dec_add(a, b) and dec_sub(a, b), for a >= b, return the sum
+// and difference; dec_mul(a, n) multiplies by a small integer n such as a percentage.
+const decimalLua = `
+	local function dec_norm(a)
+		if type(a) ~= "string" or not string.match(a, "^%d+$") then
+			return "0";
+		end
+		a = (string.gsub(a, "^0+", ""));
+		if a == "" then
+			return "0";
+		end
+		return a;
+	end
+
+	local function dec_cmp(a, b)
+		if #a ~= #b then
+			return #a < #b and -1 or 1;
+		end
+		if a == b then
+			return 0;
+		end
+		return a < b and -1 or 1;
+	end
+
+	local function dec_add(a, b)
+		local digits, carry, i, j = {}, 0, #a, #b;
+		while i > 0 or j > 0 or carry > 0 do
+			local d = carry;
+			if i > 0 then
+				d, i = d + string.byte(a, i) - 48, i - 1;
+			end
+			if j > 0 then
+				d, j = d + string.byte(b, j) - 48, j - 1;
+			end
+			digits[#digits + 1], carry = d % 10, math.floor(d / 10);
+		end
+		return dec_norm(string.reverse(table.concat(digits)));
+	end
+
+	local function dec_sub(a, b)
+		local digits, borrow, j = {}, 0, #b;
+		for i = #a, 1, -1 do
+			local d = string.byte(a, i) - 48 - borrow;
+			if j > 0 then
+				d, j = d - (string.byte(b, j) - 48), j - 1;
+			end
+			borrow = 0;
+			if d < 0 then
+				d, borrow = d + 10, 1;
+			end
+			digits[#digits + 1] = d;
+		end
+		return dec_norm(string.reverse(table.concat(digits)));
+	end
+
+	local function dec_mul(a, n)
+		local digits, carry = {}, 0;
+		for i = #a, 1, -1 do
+			local d = (string.byte(a, i) - 48) * n + carry;
+			digits[#digits + 1], carry = d % 10, math.floor(d / 10);
+		end
+		while carry > 0 do
+			digits[#digits + 1], carry = carry % 10, math.floor(carry / 10);
+		end
+		return dec_norm(string.reverse(table.concat(digits)));
+	end
+`
+
 const (
-	LuaScript = `
-		local task_key = KEYS[1];
-		if redis.call("SISMEMBER", task_key, ARGV[1]) == 1 then
-			return 1;
+	// RecordVoteScript atomically records a submission in a task's verification data and,
+	// once the performer and enough attesters have submitted, evaluates stake-weighted
+	// consensus, queues the task and marks it finished.
+	//
+	// The first recorded submission sets the task's deadline and registers it for the sweeper.
+	// In "tiebreak" mode the verdict in the performer's check decides evenly split votes, and in
+	// "veto" mode an invalid verdict turns an approval into a rejection.
+	// Every submission updates the task's persisted history. A finished task is added to the
+	// history of its operators by RecordVote once the script returned.
+	//
+	// KEYS: task_verification:<id>, task_finished:<id>, task_queue, task_deadlines,
+	//       task_deadline_heights, active_operators, then the history keys, see historyKeys
+	// ARGV: task id, role, address, submission JSON, minimum attesters, consensus threshold,
+	//       TTL in seconds, minimum total stake, quorum ("stake" or "count"), tie policy,
+	//       task type, deadline as unix seconds, deadline block height (0 for none),
+	//       verification mode ("off", "tiebreak" or "veto")
+	// Returns {status, attesters, positive weight, total weight, result, verification JSON},
+	// status being one of "finished", "duplicate", "mismatch", "recorded", "pending" or
+	// "approved"/"rejected"; the verification JSON is only returned once the vote is recorded.
+	RecordVoteScript = archiveTaskLua + decimalLua + `
+		local verification_key, finished_key, queue_key = KEYS[1], KEYS[2], KEYS[3];
+		local deadlines_key, deadline_heights_key, operators_key = KEYS[4], KEYS[5], KEYS[6];
+		local history_key, index_key = KEYS[7], KEYS[8];
+		local task_id, role, address = ARGV[1], ARGV[2], ARGV[3];
+		local min_attesters, threshold, ttl = tonumber(ARGV[5]), tonumber(ARGV[6]), tonumber(ARGV[7]);
+		local min_stake, quorum, tie, task_type = dec_norm(ARGV[8]), ARGV[9], ARGV[10], ARGV[11];
+		local deadline, deadline_height = tonumber(ARGV[12]), tonumber(ARGV[13]);
+		local verification_mode = ARGV[14];
+
+		if redis.call("EXISTS", finished_key) == 1 then
+			return {"finished", 0, "0", "0", 0};
+		end
+
+		local verification = {};
+		local existing = redis.call("GET", verification_key);
+		if existing then
+			verification = cjson.decode(existing);
+		end
+		if verification.performer == cjson.null then
+			verification.performer = nil;
+		end
+		if verification.attesters == nil or verification.attesters == cjson.null then
+			verification.attesters = {};
+		end
+		verification.consensus = nil;
+		verification.split = nil;
+		if verification.taskType and verification.taskType ~= task_type then
+			return {"mismatch", 0, "0", "0", 0};
 		end
-		redis.call("SADD", task_key, ARGV[1]);
-		return 0;
+		verification.taskType = task_type;
+
+		local submission = cjson.decode(ARGV[4]);
+		if role == "performer" then
+			if verification.performer then
+				return {"duplicate", 0, "0", "0", 0};
+			end
+			verification.performer = submission;
+		else
+			if verification.attesters[address] then
+				return {"duplicate", 0, "0", "0", 0};
+			end
+			verification.attesters[address] = submission;
+		end
+		redis.call("ZADD", operators_key, deadline, address);
+
+		if not verification.deadline then
+			verification.deadline = deadline;
+			redis.call("ZADD", deadlines_key, deadline, task_id);
+			if deadline_height > 0 then
+				verification.deadlineHeight = deadline_height;
+				redis.call("ZADD", deadline_heights_key, deadline_height, task_id);
+			end
+		end
+
+		-- stakes are decimal strings and are summed and compared as such
+		local count, positive, total = 0, "0", "0";
+		local weights = {};
+		for attester_address, attester in pairs(verification.attesters) do
+			local weight = "1";
+			if quorum == "stake" then
+				weight = dec_norm(attester.stake);
+			end
+			count = count + 1;
+			total = dec_add(total, weight);
+			if attester.result == "true" then
+				positive = dec_add(positive, weight);
+			end
+			weights[attester_address] = weight;
+		end
+
+		-- the aggregator's own verdict on the performer's block, "valid", "invalid" or "error"
+		local verdict = nil;
+		if verification.performer and type(verification.performer.check) == "table" then
+			verdict = verification.performer.check.verdict;
+		end
+
+		local status, result, decided_by = "recorded", 0, nil;
+		if verification.performer and count >= min_attesters then
+			status = "pending";
+			if total ~= "0" and (quorum ~= "stake" or dec_cmp(total, min_stake) >= 0) then
+				local needed = dec_mul(total, threshold);
+				local tied = dec_cmp(dec_mul(positive, 2), total) == 0;
+				if dec_cmp(dec_mul(positive, 100), needed) >= 0 then
+					status, result = "approved", 1;
+				elseif dec_cmp(dec_mul(dec_sub(total, positive), 100), needed) >= 0 then
+					status, result = "rejected", 0;
+				elseif tied and verification_mode == "tiebreak" and verdict == "valid" then
+					status, result, decided_by = "approved", 1, "verifier";
+				elseif tied and verification_mode == "tiebreak" and verdict == "invalid" then
+					status, result, decided_by = "rejected", 0, "verifier";
+				elseif tied and tie == "approve" then
+					status, result = "approved", 1;
+				elseif tied and tie == "reject" then
+					status, result = "rejected", 0;
+				end
+				-- enough weight voted, but no side reached the threshold
+				if status == "pending" then
+					verification.split = true;
+				end
+			end
+		end
+		if status == "approved" and verification_mode == "veto" and verdict == "invalid" then
+			status, result, decided_by = "rejected", 0, "verifier";
+		end
+
+		local old_status = verification.status;
+		verification.status = status;
+		if status == "approved" or status == "rejected" then
+			verification.consensus = {result = result, positiveStake = positive, totalStake = total, weights = weights, quorum = quorum, decidedBy = decided_by};
+		end
+		local verification_json = cjson.encode(verification);
+		redis.call("SET", verification_key, verification_json, "EX", ttl);
+		archive_task(history_key, index_key, task_id, verification_json, old_status, status);
+		if not verification.consensus then
+			return {status, count, positive, total, 0, verification_json};
+		end
+
+		local task = '{"taskID":' .. task_id .. ',"taskResult":{"operator":' .. cjson.encode(verification.performer.address) .. ',"result":' .. result .. '},"weights":' .. cjson.encode(weights) .. '}';
+		redis.call("LPUSH", queue_key, task);
+		redis.call("SET", finished_key, "1", "EX", ttl);
+		redis.call("ZREM", deadlines_key, task_id);
+		redis.call("ZREM", deadline_heights_key, task_id);
+		return {status, count, positive, total, result, verification_json};
+	`
+
+	// ExpireTaskScript atomically finalizes a task whose deadline passed without consensus.
+	//
+	// The outcome is "performer_missing" if the performer never submitted, "split_vote" if
+	// enough attesters and stake voted but no side reached the threshold, and
+	// "insufficient_attestations" otherwise. Every active operator that did not submit is
+	// recorded as missing, after operators inactive for longer than the window are dropped. The
+	// task's history is marked "expired"; ExpireTask adds it to the history of its operators.
+	// Only "performer_missing" is queued for RespondToTask, unless neutral results are submitted.
+	//
+	// KEYS: task_verification:<id>, task_finished:<id>, task_queue, task_deadlines,
+	//       task_deadline_heights, active_operators, then the history keys, see historyKeys
+	// ARGV: task id, assigned performer ("" if unknown), TTL in seconds, expiry time as unix seconds,
+	//       active operator window in seconds, "1" to queue -2 and -3 as well, see SubmittableResult
+	// Returns {outcome, result, missing operators JSON, operator, verification JSON}, outcome being
+	// "finished" or "unknown" if there was nothing to expire.
+	ExpireTaskScript = archiveTaskLua + `
+		local verification_key, finished_key, queue_key = KEYS[1], KEYS[2], KEYS[3];
+		local deadlines_key, deadline_heights_key, operators_key = KEYS[4], KEYS[5], KEYS[6];
+		local history_key, index_key = KEYS[7], KEYS[8];
+		local task_id, assigned, ttl, now = ARGV[1], ARGV[2], tonumber(ARGV[3]), tonumber(ARGV[4]);
+		local window, queue_neutral = tonumber(ARGV[5]), ARGV[6] == "1";
+
+		redis.call("ZREM", deadlines_key, task_id);
+		redis.call("ZREM", deadline_heights_key, task_id);
+		if redis.call("EXISTS", finished_key) == 1 then
+			return {"finished", 0, "[]", "", ""};
+		end
+		local existing = redis.call("GET", verification_key);
+		if not existing then
+			return {"unknown", 0, "[]", "", ""};
+		end
+
+		local verification = cjson.decode(existing);
+		if verification.performer == cjson.null then
+			verification.performer = nil;
+		end
+		if verification.attesters == nil or verification.attesters == cjson.null then
+			verification.attesters = {};
+		end
+
+		local outcome, result = "insufficient_attestations", -2;
+		if not verification.performer then
+			outcome, result = "performer_missing", -1;
+		elseif verification.split then
+			outcome, result = "split_vote", -3;
+		end
+
+		local operator = assigned;
+		if verification.performer then
+			operator = verification.performer.address;
+		end
+		local missing = {};
+		if not verification.performer and assigned ~= "" then
+			table.insert(missing, assigned);
+		end
+		redis.call("ZREMRANGEBYSCORE", operators_key, "-inf", "(" .. (now - window));
+		for _, member in ipairs(redis.call("ZRANGE", operators_key, 0, -1)) do
+			if member ~= operator and not verification.attesters[member] then
+				table.insert(missing, member);
+			end
+		end
+		table.sort(missing);
+		-- cjson encodes an empty table as an object
+		local missing_json = "[]";
+		if #missing > 0 then
+			missing_json = cjson.encode(missing);
+		end
+
+		local old_status = verification.status;
+		verification.status = "expired";
+		verification.timeout = nil;
+		local timeout = '{"outcome":' .. cjson.encode(outcome) .. ',"result":' .. result .. ',"operator":' .. cjson.encode(operator) .. ',"expiredAt":' .. now .. ',"missing":' .. missing_json .. '}';
+		local verification_json = cjson.encode(verification);
+		verification_json = string.sub(verification_json, 1, -2) .. ',"timeout":' .. timeout .. '}';
+		redis.call("SET", verification_key, verification_json, "EX", ttl);
+		archive_task(history_key, index_key, task_id, verification_json, old_status, "expired");
+
+		if result == -1 or queue_neutral then
+			local task = '{"taskID":' .. task_id .. ',"taskResult":{"operator":' .. cjson.encode(operator) .. ',"result":' .. result .. '},"weights":{},"timeout":' .. cjson.encode(outcome) .. ',"missing":' .. missing_json .. '}';
+			redis.call("LPUSH", queue_key, task);
+		end
+		redis.call("SET", finished_key, "1", "EX", ttl);
+		return {outcome, result, missing_json, operator, verification_json};
+	`
+	// ResolveTaskScript atomically finalizes a task with a result chosen by an administrator,
+	// whether or not consensus was reached, unless the task is already finished.
+	//
+	// The resolution is recorded in the task's verification data, its history is marked
+	// "resolved" and the task is queued for RespondToTask. Operator statistics are not updated.
+	//
+	// KEYS: task_verification:<id>, task_finished:<id>, task_queue, task_deadlines,
+	//       task_deadline_heights, then the history keys, see historyKeys
+	// ARGV: task id, result, operator ("" for the performer), TTL in seconds, actor, reason,
+	//       resolution time as unix seconds
+	// Returns {status, operator}, status being "resolved", "finished" if the task was already
+	// finished, or "no_operator" if no operator was given and the performer never submitted.
+	ResolveTaskScript = archiveTaskLua + `
+		local verification_key, finished_key, queue_key = KEYS[1], KEYS[2], KEYS[3];
+		local deadlines_key, deadline_heights_key = KEYS[4], KEYS[5];
+		local history_key, index_key = KEYS[6], KEYS[7];
+		local task_id, result, operator, ttl = ARGV[1], tonumber(ARGV[2]), ARGV[3], tonumber(ARGV[4]);
+
+		if redis.call("EXISTS", finished_key) == 1 then
+			return {"finished", ""};
+		end
+
+		local verification = {attesters = {}};
+		local existing = redis.call("GET", verification_key);
+		if existing then
+			verification = cjson.decode(existing);
+		end
+		if operator == "" and verification.performer then
+			operator = verification.performer.address;
+		end
+		if operator == "" then
+			return {"no_operator", ""};
+		end
+		local old_status = verification.status;
+		verification.status = "resolved";
+		verification.resolution = {result = result, operator = operator, actor = ARGV[5], reason = ARGV[6], resolvedAt = tonumber(ARGV[7])};
+		local verification_json = cjson.encode(verification);
+		redis.call("SET", verification_key, verification_json, "EX", ttl);
+		redis.call("ZREM", deadlines_key, task_id);
+		redis.call("ZREM", deadline_heights_key, task_id);
+		archive_task(history_key, index_key, task_id, verification_json, old_status, "resolved");
+
+		local task = '{"taskID":' .. task_id .. ',"taskResult":{"operator":' .. cjson.encode(operator) .. ',"result":' .. result .. '},"weights":{}}';
+		redis.call("LPUSH", queue_key, task);
+		redis.call("SET", finished_key, "1", "EX", ttl);
+		return {"resolved", operator};
 	`
-	PkTaskOperator = "task_operator:"
 	PkTaskQueue    = "task_queue"
-	PkTaskResult   = "task_result:"
 	PkTaskFinished = "task_finished:"
 
+	// PkTaskProcessing holds the tasks being submitted, PkTaskRetry the failed tasks by their
+	// next attempt in unix milliseconds, and PkTaskDeadLetter the tasks that failed too often
+	PkTaskProcessing = "task_processing"
+	PkTaskRetry      = "task_retry"
+	PkTaskDeadLetter = "task_dead_letter"
+	// PkTaskUnconfirmed holds the tasks taken for delivery whose result was not confirmed on chain
+	// yet, by the unix time they were taken
+	PkTaskUnconfirmed = "task_unconfirmed"
+
+	// PkTaskDeadlines and PkTaskDeadlineHeights index unfinished tasks by their deadline,
+	// as unix seconds and as block height
+	PkTaskDeadlines       = "task_deadlines"
+	PkTaskDeadlineHeights = "task_deadline_heights"
+
+	// PkTaskHistory holds the persisted verification data of every task, PkTaskIndex the task ids,
+	// also per status as task_index:<status>
+	PkTaskHistory = "task_history:"
+	PkTaskIndex   = "task_index"
+
+	// PkOperatorTasks holds the tasks an operator took part in, PkOperatorStats its counters
+	PkOperatorTasks = "operator_tasks:"
+	PkOperatorStats = "operator_stats:"
+
+	// PkActiveOperators holds the operators expected to respond to every task, scored by the
+	// deadline of the latest task they submitted to; operators are dropped once that is longer
+	// than ActiveOperatorWindow ago, or once they are no longer registered
+	PkActiveOperators = "active_operators"
+
+	// PkTaskAssignment caches the performer assigned to a task by the squaring contract
+	PkTaskAssignment = "task_assignment:"
+	// PkTaskNetwork caches the type of a task, the network set in the StateBank key StateBankTaskNetwork<id>
+	PkTaskNetwork = "task_network:"
+	// PkStateBankSynced is the block height the StateBank events are indexed up to
+	PkStateBankSynced = "state_bank_synced"
+
+	// ChTaskPerformer is the pub/sub channel prefix a task's performer submission is published on
+	ChTaskPerformer = "task_performer:"
+
+	// PkOperatorStake caches an operator's delegated stake per epoch, as operator_stake:<epoch>:<address>
+	PkOperatorStake = "operator_stake:"
+	// PkTaskStakeEpoch is the epoch of the stake snapshot all attesters of a task are weighted with
+	PkTaskStakeEpoch = "task_stake_epoch:"
+
+	// PkLeaderLease is the lease of the replica that delivers task results, PkLeaderFence the
+	// counter its fencing tokens are taken from, and PkLeaderBroadcast the fencing token of the
+	// leader that may have a transaction in flight
+	PkLeaderLease     = "aggregator_leader"
+	PkLeaderFence     = "aggregator_fence"
+	PkLeaderBroadcast = "aggregator_broadcast"
+
+	// PkBannedOperators is the set of operators whose submissions are rejected
+	PkBannedOperators = "banned_operators"
+
+	// PkAdminAudit is the log of authenticated admin API requests, newest first, capped at
+	// AuditLogSize entries, PkAdminAuditRejected the log of the rejected ones
+	PkAdminAudit         = "admin_audit"
+	AuditLogSize         = 10000
+	PkAdminAuditRejected = "admin_audit_rejected"
+	AuditRejectedLogSize = 1000
+
+	// PkOperatorRegistration caches an operator's registration status in the BVS directory
+	PkOperatorRegistration = "operator_registration:"
+
 	// Consensus configuration
 	MinimumAttesters   = 1
-	ConsensusThreshold = 66 // Percentage required for consensus
+	ConsensusThreshold = 66  // Percentage of the voting stake required for consensus
+	MinimumStake       = "0" // Total stake that must have voted before consensus is evaluated
+
+	// StakeEpoch is how long a stake snapshot is used to weight votes
+	StakeEpoch = time.Hour
+	// StakeDecimals is the decimals stake is expressed in, the shares of every strategy are scaled to it
+	StakeDecimals = 18
+
+	// ActiveOperatorWindow is how long an operator is expected to respond to tasks after its
+	// latest submission
+	ActiveOperatorWindow = 24 * time.Hour
+
+	// DefaultTaskTimeout is how long a task may collect submissions before it is finalized without
+	// consensus, counted from its first submission
+	DefaultTaskTimeout = 5 * time.Minute
+	// SweepInterval is how often expired tasks are looked for
+	SweepInterval = 10 * time.Second
+
+	// ReconcileInterval is how often unconfirmed tasks are compared with the results on chain
+	ReconcileInterval = 10 * time.Minute
+
+	// RegistrationTTL is how long a registered operator is cached, UnregisteredTTL how long an
+	// operator that is not registered is; registration events update the cache before either expires
+	RegistrationTTL = 10 * time.Minute
+	UnregisteredTTL = time.Minute
+	// EventStateBankUpdate is emitted by the StateBank when a key is set, StateBankTaskNetwork
+	// prefixes the keys that set the network of a task
+	EventStateBankUpdate = "wasm-UpdateState"
+	StateBankTaskNetwork = "taskNetwork."
+	// EventOperatorRegistration is emitted by the BVS directory when an operator's registration changes
+	EventOperatorRegistration = "wasm-OperatorBVSRegistrationStatusUpdated"
+
+	// MaxDeliveryAttempts is how often RespondToTask is attempted before a task is dead-lettered
+	MaxDeliveryAttempts = 5
+	// RetryBaseDelay is the backoff after the first failed attempt, doubled after every further
+	// one up to RetryMaxDelay
+	RetryBaseDelay = 5 * time.Second
+	RetryMaxDelay  = 5 * time.Minute
+	// RetryInterval is how often due retries are moved back to the queue
+	RetryInterval = time.Second
+	// QueuePollTimeout is how long the monitor blocks waiting for a task before checking whether
+	// it has to stop
+	QueuePollTimeout = 5 * time.Second
+	// CheckTimeout bounds the aggregator's own check of a performer's block
+	CheckTimeout = 15 * time.Second
+
+	// DefaultLeaseTTL is how long the leader lease lasts without renewal if [app] leaseTtl is not set
+	DefaultLeaseTTL = 15 * time.Second
+
+	// DefaultBodyLimit is the largest request body accepted if [limits] bodyLimit is not set
+	DefaultBodyLimit = 64 << 10
 )
+
+// Result codes submitted with RespondToTask
+const (
+	ResultRejected                 int64 = 0
+	ResultApproved                 int64 = 1
+	ResultPerformerMissing         int64 = -1
+	ResultInsufficientAttestations int64 = -2
+	ResultSplitVote                int64 = -3
+)
+
+// SubmittableResult reports whether a result code is submitted with RespondToTask.
+//
+// Squaring contracts before version 2.1.0 count every result other than ResultApproved against
+// the performer, so ResultInsufficientAttestations and ResultSplitVote are only recorded in
+// Redis until [chain] neutralResults is set.
+func SubmittableResult(result int64) bool {
+	if result == ResultInsufficientAttestations || result == ResultSplitVote {
+		return C.Chain.NeutralResults
+	}
+	return true
+}
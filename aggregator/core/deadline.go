@@ -0,0 +1,128 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	TimeoutPerformerMissing         = "performer_missing"         // the performer never submitted
+	TimeoutInsufficientAttestations = "insufficient_attestations" // too few attesters or too little stake voted
+	TimeoutSplitVote                = "split_vote"                // enough votes, but no side reached the threshold
+)
+
+var expireTask = redis.NewScript(ExpireTaskScript)
+
+// Deadline is when a task is finalized without consensus.
+type Deadline struct {
+	Time time.Time
+	// Height is the block height the task expires at, 0 for none
+	Height int64
+}
+
+// NewDeadline returns the deadline of a task first seen at now under policy.
+//
+// now is the time of the task's first submission.
+// height is the latest block height, only used if the policy sets TimeoutBlocks.
+func NewDeadline(now time.Time, height int64, policy ConsensusPolicy) Deadline {
+	deadline := Deadline{Time: now.Add(time.Duration(policy.Timeout) * time.Second)}
+	if policy.TimeoutBlocks > 0 {
+		deadline.Height = height + policy.TimeoutBlocks
+	}
+	return deadline
+}
+
+// ExpiredTasks returns the unfinished tasks whose deadline passed.
+//
+// ctx is the context for the Redis calls.
+// now is the current time.
+// height is the latest block height, or 0 to skip block height deadlines.
+// Returns the task ids, or an error if Redis fails.
+func ExpiredTasks(ctx context.Context, now time.Time, height int64) ([]uint64, error) {
+	members, err := S.RedisConn.ZRangeByScore(ctx, PkTaskDeadlines, &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(now.Unix(), 10)}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task deadlines: %v", err)
+	}
+	if height > 0 {
+		byHeight, err := S.RedisConn.ZRangeByScore(ctx, PkTaskDeadlineHeights, &redis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(height, 10)}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read task deadline heights: %v", err)
+		}
+		members = append(members, byHeight...)
+	}
+
+	seen := make(map[uint64]bool, len(members))
+	taskIds := make([]uint64, 0, len(members))
+	for _, member := range members {
+		taskId, err := strconv.ParseUint(member, 10, 64)
+		if err != nil || seen[taskId] {
+			continue
+		}
+		seen[taskId] = true
+		taskIds = append(taskIds, taskId)
+	}
+	return taskIds, nil
+}
+
+// HasDeadlineHeights reports whether any unfinished task expires at a block height.
+func HasDeadlineHeights(ctx context.Context) (bool, error) {
+	n, err := S.RedisConn.ZCard(ctx, PkTaskDeadlineHeights).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to read task deadline heights: %v", err)
+	}
+	return n > 0, nil
+}
+
+// ExpireTask atomically finalizes a task that reached its deadline without consensus.
+//
+// The task is marked finished and queued with the result code of its timeout outcome, if
+// SubmittableResult accepts it, and the active operators that never submitted are recorded in
+// its verification data.
+// ctx is the context for the Redis call.
+// taskId is the unique identifier of the task.
+// performer is the operator assigned to the task, or "" if unknown.
+// now is the expiry time recorded with the outcome.
+// Returns the timeout, nil if the task was already finished or is unknown, or an error if Redis fails.
+func ExpireTask(ctx context.Context, taskId uint64, performer string, now time.Time) (*TaskTimeout, error) {
+	keys := []string{
+		fmt.Sprintf("%s%d", PkTaskVerification, taskId),
+		fmt.Sprintf("%s%d", PkTaskFinished, taskId),
+		PkTaskQueue,
+		PkTaskDeadlines,
+		PkTaskDeadlineHeights,
+		PkActiveOperators,
+	}
+	keys = append(keys, historyKeys(taskId)...)
+	queueNeutral := "0"
+	if C.Chain.NeutralResults {
+		queueNeutral = "1"
+	}
+	res, err := expireTask.Run(ctx, S.RedisConn, keys, taskId, performer, int64(taskTTL.Seconds()), now.Unix(),
+		int64(ActiveOperatorWindow.Seconds()), queueNeutral).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire task: %v", err)
+	}
+	if len(res) != 5 {
+		return nil, fmt.Errorf("unexpected expire script reply: %v", res)
+	}
+
+	outcome, _ := res[0].(string)
+	if outcome == "finished" || outcome == "unknown" {
+		return nil, nil
+	}
+	result, _ := res[1].(int64)
+	missingStr, _ := res[2].(string)
+	operator, _ := res[3].(string)
+	timeout := &TaskTimeout{Outcome: outcome, Result: result, Operator: operator, ExpiredAt: now.Unix()}
+	if err := json.Unmarshal([]byte(missingStr), &timeout.Missing); err != nil {
+		return nil, fmt.Errorf("failed to parse missing operators: %v", err)
+	}
+	verificationStr, _ := res[4].(string)
+	archiveOperators(ctx, taskId, verificationStr, result, timeout.Missing)
+	return timeout, nil
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Tasks are delivered at least once with the reliable queue pattern: a task is moved from
+// task_queue to task_processing while it is submitted, and only removed once RespondToTask
+// succeeded. Failed tasks wait in task_retry with exponential backoff and are moved to
+// task_dead_letter after MaxDeliveryAttempts.
+
+// requeueDueRetries moves the tasks whose retry time passed back to the queue.
+//
+// KEYS: task_retry, task_queue
+// ARGV: now as unix milliseconds, maximum number of tasks to move
+// Returns the number of tasks moved.
+var requeueDueRetries = redis.NewScript(`
+	local due = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, tonumber(ARGV[2]));
+	for _, item in ipairs(due) do
+		redis.call("ZREM", KEYS[1], item);
+		redis.call("LPUSH", KEYS[2], item);
+	end
+	return #due;
+`)
+
+// redriveDeadLetter moves a dead letter back to the queue, unless it was redriven already.
+//
+// KEYS: task_dead_letter, task_queue
+// ARGV: dead letter, task to queue
+// Returns 1 if the task was queued, 0 otherwise.
+var redriveDeadLetter = redis.NewScript(`
+	if redis.call("LREM", KEYS[1], 1, ARGV[1]) == 0 then
+		return 0;
+	end
+	redis.call("LPUSH", KEYS[2], ARGV[2]);
+	return 1;
+`)
+
+// NextTask blocks until a task is queued and moves it to the processing list.
+//
+// ctx is the context for the Redis call.
+// timeout is how long to wait, 0 to wait forever.
+// Returns the raw queue item, or redis.Nil on timeout, or an error if Redis fails.
+func NextTask(ctx context.Context, timeout time.Duration) (string, error) {
+	// tasks are pushed on the left and taken from the right, oldest first
+	return S.RedisConn.BLMove(ctx, PkTaskQueue, PkTaskProcessing, "RIGHT", "LEFT", timeout).Result()
+}
+
+// TryNextTask moves the oldest queued task to the processing list without blocking.
+//
+// ctx is the context for the Redis call.
+// Returns the raw queue item, redis.Nil if the queue is empty, or an error if Redis fails.
+func TryNextTask(ctx context.Context) (string, error) {
+	return S.RedisConn.LMove(ctx, PkTaskQueue, PkTaskProcessing, "RIGHT", "LEFT").Result()
+}
+
+// AckTask removes a delivered task from the processing list.
+//
+// ctx is the context for the Redis call.
+// item is the raw queue item returned by NextTask.
+// Returns an error if Redis fails.
+func AckTask(ctx context.Context, item string) error {
+	if err := S.RedisConn.LRem(ctx, PkTaskProcessing, 1, item).Err(); err != nil {
+		return fmt.Errorf("failed to ack task: %v", err)
+	}
+	return nil
+}
+
+// RetryDelay returns the backoff before the given delivery attempt is retried.
+//
+// attempts is the number of failed attempts so far, at least 1.
+func RetryDelay(attempts int) time.Duration {
+	delay := RetryBaseDelay
+	for i := 1; i < attempts && delay < RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > RetryMaxDelay {
+		delay = RetryMaxDelay
+	}
+	return delay
+}
+
+// FailTask records a failed delivery attempt of a task.
+//
+// The task is scheduled for a retry after RetryDelay, or moved to the dead-letter list once it
+// failed MaxDeliveryAttempts times. Removing it from the processing list and scheduling it
+// happen in one transaction.
+// ctx is the context for the Redis call.
+// item is the raw queue item returned by NextTask.
+// task is the parsed task, or nil if item cannot be parsed, which dead-letters it right away.
+// cause is the delivery error.
+// now is the time of the failed attempt.
+// Returns whether the task was dead-lettered, or an error if Redis fails.
+func FailTask(ctx context.Context, item string, task *Task, cause error, now time.Time) (bool, error) {
+	deadLetter := task == nil
+	next := item
+	if task != nil {
+		failed := *task
+		failed.Attempts++
+		failed.LastError = cause.Error()
+		deadLetter = failed.Attempts >= MaxDeliveryAttempts
+		b, err := json.Marshal(failed)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal task: %v", err)
+		}
+		next = string(b)
+		now = now.Add(RetryDelay(failed.Attempts))
+	}
+
+	_, err := S.RedisConn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.LRem(ctx, PkTaskProcessing, 1, item)
+		if deadLetter {
+			pipe.LPush(ctx, PkTaskDeadLetter, next)
+		} else {
+			pipe.ZAdd(ctx, PkTaskRetry, &redis.Z{Score: float64(now.UnixMilli()), Member: next})
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to reschedule task: %v", err)
+	}
+	return deadLetter, nil
+}
+
+// RequeueDueRetries moves the tasks whose retry time passed back to the queue.
+//
+// ctx is the context for the Redis call.
+// now is the current time.
+// Returns the number of tasks moved, or an error if Redis fails.
+func RequeueDueRetries(ctx context.Context, now time.Time) (int64, error) {
+	n, err := requeueDueRetries.Run(ctx, S.RedisConn, []string{PkTaskRetry, PkTaskQueue}, now.UnixMilli(), 100).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue retries: %v", err)
+	}
+	return n, nil
+}
+
+// RecoverProcessingTasks moves the tasks left in the processing list by a stopped monitor back
+// to the front of the queue.
+//
+// It must only run while no monitor is delivering tasks.
+// ctx is the context for the Redis calls.
+// Returns the number of tasks recovered, or an error if Redis fails.
+func RecoverProcessingTasks(ctx context.Context) (int, error) {
+	var n int
+	for {
+		err := S.RedisConn.LMove(ctx, PkTaskProcessing, PkTaskQueue, "RIGHT", "RIGHT").Err()
+		if err == redis.Nil {
+			return n, nil
+		}
+		if err != nil {
+			return n, fmt.Errorf("failed to recover processing tasks: %v", err)
+		}
+		n++
+	}
+}
+
+// DeadLetters returns the dead-lettered tasks, newest first.
+//
+// ctx is the context for the Redis call.
+// Returns the raw dead letters, or an error if Redis fails.
+func DeadLetters(ctx context.Context) ([]string, error) {
+	items, err := S.RedisConn.LRange(ctx, PkTaskDeadLetter, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %v", err)
+	}
+	return items, nil
+}
+
+// RedriveDeadLetters moves dead-lettered tasks back to the queue with a fresh attempt count.
+//
+// ctx is the context for the Redis calls.
+// taskId selects the task to redrive, or all tasks if nil.
+// Returns the ids of the tasks queued again, or an error if Redis fails.
+func RedriveDeadLetters(ctx context.Context, taskId *uint64) ([]uint64, error) {
+	items, err := DeadLetters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	redriven := []uint64{}
+	for _, item := range items {
+		var task Task
+		if err := json.Unmarshal([]byte(item), &task); err != nil {
+			continue
+		}
+		if taskId != nil && task.TaskId != *taskId {
+			continue
+		}
+		task.Attempts = 0
+		task.LastError = ""
+		b, err := json.Marshal(task)
+		if err != nil {
+			return redriven, fmt.Errorf("failed to marshal task: %v", err)
+		}
+		ok, err := redriveDeadLetter.Run(ctx, S.RedisConn, []string{PkTaskDeadLetter, PkTaskQueue}, item, string(b)).Int()
+		if err != nil {
+			return redriven, fmt.Errorf("failed to redrive task %s: %v", strconv.FormatUint(task.TaskId, 10), err)
+		}
+		if ok == 1 {
+			redriven = append(redriven, task.TaskId)
+		}
+	}
+	return redriven, nil
+}
@@ -0,0 +1,262 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// TaskStatuses are the statuses tasks can be listed by.
+var TaskStatuses = []string{VoteRecorded, VotePending, VoteApproved, VoteRejected, VoteExpired, VoteResolved}
+
+// TaskRecord is the persisted history of a task.
+type TaskRecord struct {
+	TaskId uint64 `json:"taskId"`
+	TaskVerification
+}
+
+// OperatorStats counts an operator's participation in finished tasks.
+type OperatorStats struct {
+	Performed int64 `json:"performed"` // tasks performed
+	Approved  int64 `json:"approved"`  // performed tasks the attesters approved
+	Attested  int64 `json:"attested"`  // tasks attested
+	Decided   int64 `json:"decided"`   // attested tasks that reached consensus
+	Agreed    int64 `json:"agreed"`    // decided tasks the attester voted with the consensus on
+	Missed    int64 `json:"missed"`    // expired tasks the operator never responded to
+	// AgreementRate is Agreed / Decided, 0 without decided tasks
+	AgreementRate float64 `json:"agreementRate"`
+}
+
+// OperatorTask is an operator's part in a task.
+type OperatorTask struct {
+	TaskId uint64 `json:"taskId"`
+	Role   string `json:"role"`             // "performer", "attester" or "missing"
+	Result string `json:"result,omitempty"` // the operator's submission
+	Status string `json:"status"`           // the task's status
+	// FinalResult is the result the task was finalized with, Agreed whether an attester voted
+	// with the consensus; both are only set once known
+	FinalResult *int64 `json:"finalResult,omitempty"`
+	Agreed      *bool  `json:"agreed,omitempty"`
+}
+
+// LoadTask returns the persisted history of a task.
+//
+// ctx is the context for the Redis call.
+// taskId is the unique identifier of the task.
+// Returns the task, redis.Nil if the task is unknown, or an error if Redis fails.
+func LoadTask(ctx context.Context, taskId uint64) (*TaskRecord, error) {
+	data, err := S.RedisConn.Get(ctx, fmt.Sprintf("%s%d", PkTaskHistory, taskId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	record := &TaskRecord{TaskId: taskId}
+	if err := json.Unmarshal([]byte(data), &record.TaskVerification); err != nil {
+		return nil, fmt.Errorf("failed to parse task history: %v", err)
+	}
+	return record, nil
+}
+
+// ListTasks returns a page of tasks, newest first.
+//
+// ctx is the context for the Redis calls.
+// status filters the tasks by status, "" for all tasks.
+// offset and limit select the page.
+// Returns the tasks, the number of tasks matching the filter, or an error if Redis fails.
+func ListTasks(ctx context.Context, status string, offset, limit int64) ([]TaskRecord, int64, error) {
+	index := PkTaskIndex
+	if status != "" {
+		index = PkTaskIndex + ":" + status
+	}
+	taskIds, total, err := page(ctx, index, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	records, err := loadTasks(ctx, taskIds)
+	if err != nil {
+		return nil, 0, err
+	}
+	tasks := make([]TaskRecord, 0, len(records))
+	for _, record := range records {
+		if record != nil {
+			tasks = append(tasks, *record)
+		}
+	}
+	return tasks, total, nil
+}
+
+// OperatorHistory returns an operator's counters and a page of the tasks it took part in, newest first.
+//
+// ctx is the context for the Redis calls.
+// address is the operator's address.
+// offset and limit select the page.
+// Returns the counters, the tasks, the number of tasks, or an error if Redis fails.
+func OperatorHistory(ctx context.Context, address string, offset, limit int64) (OperatorStats, []OperatorTask, int64, error) {
+	var stats OperatorStats
+	counters, err := S.RedisConn.HGetAll(ctx, PkOperatorStats+address).Result()
+	if err != nil {
+		return stats, nil, 0, fmt.Errorf("failed to read operator stats: %v", err)
+	}
+	for field, dest := range map[string]*int64{
+		"performed": &stats.Performed,
+		"approved":  &stats.Approved,
+		"attested":  &stats.Attested,
+		"decided":   &stats.Decided,
+		"agreed":    &stats.Agreed,
+		"missed":    &stats.Missed,
+	} {
+		*dest, _ = strconv.ParseInt(counters[field], 10, 64)
+	}
+	if stats.Decided > 0 {
+		stats.AgreementRate = float64(stats.Agreed) / float64(stats.Decided)
+	}
+
+	taskIds, total, err := page(ctx, PkOperatorTasks+address, offset, limit)
+	if err != nil {
+		return stats, nil, 0, err
+	}
+	records, err := loadTasks(ctx, taskIds)
+	if err != nil {
+		return stats, nil, 0, err
+	}
+	tasks := make([]OperatorTask, 0, len(records))
+	for _, record := range records {
+		if record != nil {
+			tasks = append(tasks, operatorTask(record, address))
+		}
+	}
+	return stats, tasks, total, nil
+}
+
+// operatorTask describes the part address took in a task.
+func operatorTask(record *TaskRecord, address string) OperatorTask {
+	task := OperatorTask{TaskId: record.TaskId, Role: "missing", Status: record.Status}
+	switch {
+	case record.Consensus != nil:
+		task.FinalResult = &record.Consensus.Result
+	case record.Timeout != nil:
+		task.FinalResult = &record.Timeout.Result
+	}
+
+	if record.Performer != nil && record.Performer.Address == address {
+		task.Role = RolePerformer
+		task.Result = record.Performer.Result
+	} else if attester, ok := record.Attesters[address]; ok {
+		task.Role = RoleAttester
+		task.Result = attester.Result
+		if record.Consensus != nil {
+			agreed := (attester.Result == "true") == (record.Consensus.Result == ResultApproved)
+			task.Agreed = &agreed
+		}
+	}
+	return task
+}
+
+// page returns a page of the task ids in a sorted set, highest first.
+func page(ctx context.Context, key string, offset, limit int64) ([]string, int64, error) {
+	total, err := S.RedisConn.ZCard(ctx, key).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count tasks: %v", err)
+	}
+	if limit <= 0 || offset >= total {
+		return nil, total, nil
+	}
+	taskIds, err := S.RedisConn.ZRevRange(ctx, key, offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list tasks: %v", err)
+	}
+	return taskIds, total, nil
+}
+
+// loadTasks reads the history of several tasks at once; unknown tasks are nil.
+func loadTasks(ctx context.Context, taskIds []string) ([]*TaskRecord, error) {
+	if len(taskIds) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(taskIds))
+	for i, taskId := range taskIds {
+		keys[i] = PkTaskHistory + taskId
+	}
+	values, err := S.RedisConn.MGet(ctx, keys...).Result()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to read task history: %v", err)
+	}
+	records := make([]*TaskRecord, len(values))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+		taskId, err := strconv.ParseUint(taskIds[i], 10, 64)
+		if err != nil {
+			continue
+		}
+		record := &TaskRecord{TaskId: taskId}
+		if err := json.Unmarshal([]byte(data), &record.TaskVerification); err != nil {
+			return nil, fmt.Errorf("failed to parse task history of %d: %v", taskId, err)
+		}
+		records[i] = record
+	}
+	return records, nil
+}
+
+// historyKeys returns the keys archive_task writes for a task: its history, the task index and
+// the index of every status, in this order.
+func historyKeys(taskId uint64) []string {
+	keys := []string{fmt.Sprintf("%s%d", PkTaskHistory, taskId), PkTaskIndex}
+	for _, status := range TaskStatuses {
+		keys = append(keys, PkTaskIndex+":"+status)
+	}
+	return keys
+}
+
+// archiveOperators adds a finished task to the history of its operators and updates their counters.
+//
+// A task finishes once, so the history is written after the script that finished it, in a
+// pipeline of its own; the operators' keys are not known before the script ran. A failure is
+// logged, the task itself is already finished.
+// ctx is the context for the Redis calls.
+// taskId is the unique identifier of the task.
+// verificationJSON is the task's verification data as the script stored it.
+// result is the result the task was finalized with.
+// missing are the operators that never responded.
+// No return values.
+func archiveOperators(ctx context.Context, taskId uint64, verificationJSON string, result int64, missing []string) {
+	var verification TaskVerification
+	if err := json.Unmarshal([]byte(verificationJSON), &verification); err != nil {
+		L.Error(fmt.Sprintf("Failed to parse verification of task %d, due to {%s}", taskId, err))
+		return
+	}
+	member := &redis.Z{Score: float64(taskId), Member: fmt.Sprint(taskId)}
+	_, err := S.RedisConn.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		participate := func(address string, field string) {
+			pipe.ZAdd(ctx, PkOperatorTasks+address, member)
+			pipe.HIncrBy(ctx, PkOperatorStats+address, field, 1)
+		}
+		if verification.Performer != nil {
+			participate(verification.Performer.Address, "performed")
+			if result == ResultApproved {
+				pipe.HIncrBy(ctx, PkOperatorStats+verification.Performer.Address, "approved", 1)
+			}
+		}
+		for address, attester := range verification.Attesters {
+			participate(address, "attested")
+			// only tasks that reached consensus have a result to agree with
+			if result == ResultRejected || result == ResultApproved {
+				pipe.HIncrBy(ctx, PkOperatorStats+address, "decided", 1)
+				if (attester.Result == "true") == (result == ResultApproved) {
+					pipe.HIncrBy(ctx, PkOperatorStats+address, "agreed", 1)
+				}
+			}
+		}
+		for _, address := range missing {
+			participate(address, "missed")
+		}
+		return nil
+	})
+	if err != nil {
+		L.Error(fmt.Sprintf("Failed to archive operators of task %d, due to {%s}", taskId, err))
+	}
+}
@@ -0,0 +1,267 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Replicas elect the aggregator that delivers task results with a lease in Redis. The lease
+// is the hash aggregator_leader with the holder and its fencing token, and expires unless the
+// holder renews it. Every new lease gets a token greater than all before it, from the counter
+// aggregator_fence, so a leader that lost its lease can tell by comparing tokens.
+
+// ErrLeaseLost is returned when the caller's fencing token is no longer the current one.
+var ErrLeaseLost = errors.New("leader lease lost")
+
+// acquireLease acquires the lease for a holder, or renews it if the holder already has it.
+//
+// KEYS: aggregator_leader, aggregator_fence
+// ARGV: holder, lease TTL in milliseconds
+// Returns the holder's fencing token, or 0 if another holder has the lease.
+var acquireLease = redis.NewScript(`
+	local holder = redis.call("HGET", KEYS[1], "holder");
+	if holder == ARGV[1] then
+		redis.call("PEXPIRE", KEYS[1], ARGV[2]);
+		return tonumber(redis.call("HGET", KEYS[1], "token"));
+	end
+	if holder then
+		return 0;
+	end
+	local token = redis.call("INCR", KEYS[2]);
+	redis.call("HSET", KEYS[1], "holder", ARGV[1], "token", token);
+	redis.call("PEXPIRE", KEYS[1], ARGV[2]);
+	return token;
+`)
+
+// releaseLease releases the lease if the holder still has it.
+//
+// KEYS: aggregator_leader
+// ARGV: holder
+// Returns 1 if the lease was released, 0 otherwise.
+var releaseLease = redis.NewScript(`
+	if redis.call("HGET", KEYS[1], "holder") ~= ARGV[1] then
+		return 0;
+	end
+	redis.call("DEL", KEYS[1]);
+	return 1;
+`)
+
+// beginBroadcast marks that the holder of a fencing token is about to broadcast a transaction,
+// if the token is still the current one.
+//
+// KEYS: aggregator_leader, aggregator_broadcast
+// ARGV: fencing token, how long the transaction may be in flight in milliseconds
+// Returns 1 if the broadcast may go ahead, 0 if the token is no longer current.
+var beginBroadcast = redis.NewScript(`
+	if redis.call("HGET", KEYS[1], "token") ~= ARGV[1] then
+		return 0;
+	end
+	redis.call("SET", KEYS[2], ARGV[1], "PX", ARGV[2]);
+	return 1;
+`)
+
+// endBroadcast clears the broadcast mark if it is still the one of the given fencing token.
+//
+// KEYS: aggregator_broadcast
+// ARGV: fencing token
+// Returns 1 if the mark was cleared, 0 otherwise.
+var endBroadcast = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) ~= ARGV[1] then
+		return 0;
+	end
+	redis.call("DEL", KEYS[1]);
+	return 1;
+`)
+
+// returnTasks moves tasks from the processing list back to the front of the queue, unless
+// another monitor already did.
+//
+// KEYS: task_processing, task_queue
+// ARGV: the raw queue items
+// Returns the number of tasks moved.
+var returnTasks = redis.NewScript(`
+	local n = 0;
+	for _, item in ipairs(ARGV) do
+		if redis.call("LREM", KEYS[1], 1, item) > 0 then
+			redis.call("RPUSH", KEYS[2], item);
+			n = n + 1;
+		end
+	end
+	return n;
+`)
+
+// Leader is the current holder of the leader lease.
+type Leader struct {
+	Holder string `json:"holder"`
+	Token  int64  `json:"token"`
+	TTL    int64  `json:"ttl"` // milliseconds until the lease expires unless renewed
+}
+
+// AcquireLease acquires the leader lease for holder, or renews it if holder already has it.
+//
+// ctx is the context for the Redis call.
+// holder identifies the replica.
+// ttl is how long the lease lasts unless it is renewed.
+// Returns the holder's fencing token, 0 if another replica has the lease, or an error if Redis fails.
+func AcquireLease(ctx context.Context, holder string, ttl time.Duration) (int64, error) {
+	token, err := acquireLease.Run(ctx, S.RedisConn, []string{PkLeaderLease, PkLeaderFence}, holder, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to acquire leader lease: %v", err)
+	}
+	return token, nil
+}
+
+// ReleaseLease gives up the leader lease if holder has it, so another replica can take over
+// without waiting for it to expire.
+//
+// ctx is the context for the Redis call.
+// holder identifies the replica.
+// Returns an error if Redis fails.
+func ReleaseLease(ctx context.Context, holder string) error {
+	if err := releaseLease.Run(ctx, S.RedisConn, []string{PkLeaderLease}, holder).Err(); err != nil {
+		return fmt.Errorf("failed to release leader lease: %v", err)
+	}
+	return nil
+}
+
+// CurrentLeader returns the holder of the leader lease.
+//
+// ctx is the context for the Redis calls.
+// Returns the leader, redis.Nil if no replica has the lease, or an error if Redis fails.
+func CurrentLeader(ctx context.Context) (*Leader, error) {
+	lease, err := S.RedisConn.HGetAll(ctx, PkLeaderLease).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leader lease: %v", err)
+	}
+	if lease["holder"] == "" {
+		return nil, redis.Nil
+	}
+	token, err := strconv.ParseInt(lease["token"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fencing token: %s", lease["token"])
+	}
+	ttl, err := S.RedisConn.PTTL(ctx, PkLeaderLease).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leader lease: %v", err)
+	}
+	return &Leader{Holder: lease["holder"], Token: token, TTL: ttl.Milliseconds()}, nil
+}
+
+// CheckFence checks that token is the fencing token of the current lease.
+//
+// ctx is the context for the Redis call.
+// token is the fencing token the caller acquired the lease with.
+// Returns ErrLeaseLost if another replica took over or the lease expired, or an error if Redis fails.
+func CheckFence(ctx context.Context, token int64) error {
+	current, err := S.RedisConn.HGet(ctx, PkLeaderLease, "token").Result()
+	if err == redis.Nil {
+		return ErrLeaseLost
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read fencing token: %v", err)
+	}
+	if current != strconv.FormatInt(token, 10) {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// BeginBroadcast checks that token is the fencing token of the current lease, and marks that
+// its holder may have a transaction in flight for up to ttl, see AwaitBroadcast.
+//
+// The check only fences off a leader that lost the lease before it: Redis cannot stop a
+// transaction that is already broadcast, or one broadcast right after the check.
+// ctx is the context for the Redis call.
+// token is the fencing token the caller acquired the lease with.
+// ttl is how long the transaction may be in flight, unless EndBroadcast is called.
+// Returns ErrLeaseLost if another replica took over or the lease expired, or an error if Redis fails.
+func BeginBroadcast(ctx context.Context, token int64, ttl time.Duration) error {
+	ok, err := beginBroadcast.Run(ctx, S.RedisConn, []string{PkLeaderLease, PkLeaderBroadcast}, token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to mark broadcast: %v", err)
+	}
+	if ok == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// EndBroadcast clears the mark of BeginBroadcast once the transaction was included or failed.
+//
+// ctx is the context for the Redis call.
+// token is the fencing token passed to BeginBroadcast.
+// Returns an error if Redis fails.
+func EndBroadcast(ctx context.Context, token int64) error {
+	if err := endBroadcast.Run(ctx, S.RedisConn, []string{PkLeaderBroadcast}, token).Err(); err != nil {
+		return fmt.Errorf("failed to clear broadcast: %v", err)
+	}
+	return nil
+}
+
+// AwaitBroadcast waits until no transaction of a previous leader may be in flight, i.e. the mark
+// of BeginBroadcast was cleared or expired.
+//
+// ctx is the context for the Redis calls.
+// Returns how long it waited, or an error if ctx is done or Redis fails.
+func AwaitBroadcast(ctx context.Context) (time.Duration, error) {
+	start := time.Now()
+	for {
+		ttl, err := S.RedisConn.PTTL(ctx, PkLeaderBroadcast).Result()
+		if err != nil {
+			return time.Since(start), fmt.Errorf("failed to read broadcast: %v", err)
+		}
+		// PTTL is negative if the mark does not exist
+		if ttl <= 0 {
+			return time.Since(start), nil
+		}
+		if ttl > time.Second {
+			ttl = time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return time.Since(start), ctx.Err()
+		case <-time.After(ttl):
+		}
+	}
+}
+
+// ReturnTasks moves tasks taken by NextTask back to the front of the queue without
+// counting an attempt, e.g. because the leader lost its lease before submitting them.
+//
+// ctx is the context for the Redis call.
+// items are the raw queue items.
+// Returns the number of tasks moved, or an error if Redis fails.
+func ReturnTasks(ctx context.Context, items []string) (int64, error) {
+	if len(items) == 0 {
+		return 0, nil
+	}
+	args := make([]interface{}, len(items))
+	for i, item := range items {
+		args[i] = item
+	}
+	n, err := returnTasks.Run(ctx, S.RedisConn, []string{PkTaskProcessing, PkTaskQueue}, args...).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to return tasks: %v", err)
+	}
+	return n, nil
+}
+
+type fenceKey struct{}
+
+// WithFence returns a context carrying the fencing token of the leader lease.
+func WithFence(ctx context.Context, token int64) context.Context {
+	return context.WithValue(ctx, fenceKey{}, token)
+}
+
+// Fence returns the fencing token carried by ctx, see WithFence.
+//
+// Returns false if ctx carries no token.
+func Fence(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(fenceKey{}).(int64)
+	return token, ok
+}
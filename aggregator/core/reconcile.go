@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// ErrResultNotSubmittable is returned when a task's result is only recorded in Redis, see
+// SubmittableResult.
+var ErrResultNotSubmittable = errors.New("result is not accepted by the squaring contract, see [chain] neutralResults")
+
+// TrackDeliveries records tasks taken from the queue for delivery as unconfirmed, until
+// ConfirmTasks is called for them once their result landed.
+//
+// ctx is the context for the Redis call.
+// taskIds are the tasks taken for delivery.
+// now is when they were taken.
+// Returns an error if Redis fails.
+func TrackDeliveries(ctx context.Context, taskIds []uint64, now time.Time) error {
+	if len(taskIds) == 0 {
+		return nil
+	}
+	members := make([]*redis.Z, len(taskIds))
+	for i, taskId := range taskIds {
+		members[i] = &redis.Z{Score: float64(now.Unix()), Member: taskId}
+	}
+	if err := S.RedisConn.ZAdd(ctx, PkTaskUnconfirmed, members...).Err(); err != nil {
+		return fmt.Errorf("failed to track deliveries: %v", err)
+	}
+	return nil
+}
+
+// ConfirmTasks marks tasks as settled, so reconciliation no longer checks them: their result
+// landed on chain, or there is nothing left to submit.
+//
+// ctx is the context for the Redis call.
+// taskIds are the settled tasks.
+// Returns an error if Redis fails.
+func ConfirmTasks(ctx context.Context, taskIds ...uint64) error {
+	if len(taskIds) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(taskIds))
+	for i, taskId := range taskIds {
+		members[i] = taskId
+	}
+	if err := S.RedisConn.ZRem(ctx, PkTaskUnconfirmed, members...).Err(); err != nil {
+		return fmt.Errorf("failed to confirm tasks: %v", err)
+	}
+	return nil
+}
+
+// UnconfirmedTasks returns the tasks taken for delivery whose result was not confirmed yet.
+//
+// Tasks taken longer ago than the verification data is kept are dropped, as they cannot be
+// queued again anyway.
+// ctx is the context for the Redis calls.
+// now is the current time.
+// Returns the task ids, or an error if Redis fails.
+func UnconfirmedTasks(ctx context.Context, now time.Time) ([]uint64, error) {
+	expired := strconv.FormatInt(now.Add(-taskTTL).Unix(), 10)
+	if err := S.RedisConn.ZRemRangeByScore(ctx, PkTaskUnconfirmed, "-inf", "("+expired).Err(); err != nil {
+		return nil, fmt.Errorf("failed to drop expired unconfirmed tasks: %v", err)
+	}
+	members, err := S.RedisConn.ZRange(ctx, PkTaskUnconfirmed, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read unconfirmed tasks: %v", err)
+	}
+	taskIds := make([]uint64, 0, len(members))
+	for _, member := range members {
+		taskId, err := strconv.ParseUint(member, 10, 64)
+		if err != nil {
+			continue
+		}
+		taskIds = append(taskIds, taskId)
+	}
+	return taskIds, nil
+}
+
+// PendingDeliveries returns the tasks waiting to be submitted, by task id.
+//
+// A task is pending while it is queued, processing, waiting for a retry or dead-lettered.
+// ctx is the context for the Redis calls.
+// Returns the raw items of every pending task, or an error if Redis fails.
+func PendingDeliveries(ctx context.Context) (map[uint64][]string, error) {
+	pending := map[uint64][]string{}
+	add := func(items []string) {
+		for _, item := range items {
+			var task Task
+			if json.Unmarshal([]byte(item), &task) == nil {
+				pending[task.TaskId] = append(pending[task.TaskId], item)
+			}
+		}
+	}
+	for _, key := range []string{PkTaskQueue, PkTaskProcessing, PkTaskDeadLetter} {
+		items, err := S.RedisConn.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", key, err)
+		}
+		add(items)
+	}
+	items, err := S.RedisConn.ZRange(ctx, PkTaskRetry, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", PkTaskRetry, err)
+	}
+	add(items)
+	return pending, nil
+}
+
+// DropDeliveries removes the given items of a task from the queue, the retries and the
+// dead-letter list, e.g. because its result already landed on chain.
+//
+// Items in the processing list are left to the monitor delivering them.
+// ctx is the context for the Redis calls.
+// items are the raw items returned by PendingDeliveries.
+// Returns the number of items removed, or an error if Redis fails.
+func DropDeliveries(ctx context.Context, items []string) (int64, error) {
+	var removed int64
+	for _, item := range items {
+		cmds, err := S.RedisConn.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LRem(ctx, PkTaskQueue, 0, item)
+			pipe.ZRem(ctx, PkTaskRetry, item)
+			pipe.LRem(ctx, PkTaskDeadLetter, 0, item)
+			return nil
+		})
+		if err != nil {
+			return removed, fmt.Errorf("failed to drop deliveries: %v", err)
+		}
+		for _, cmd := range cmds {
+			removed += cmd.(*redis.IntCmd).Val()
+		}
+	}
+	return removed, nil
+}
+
+// RequeueFinishedTask queues a finished task again from its verification data.
+//
+// ctx is the context for the Redis calls.
+// taskId is the unique identifier of the task.
+// Returns the queued task, redis.Nil if its verification data expired, ErrResultNotSubmittable
+// if its result is only recorded in Redis, or an error if the task has no final outcome or
+// Redis fails.
+func RequeueFinishedTask(ctx context.Context, taskId uint64) (*Task, error) {
+	data, err := S.RedisConn.Get(ctx, fmt.Sprintf("%s%d", PkTaskVerification, taskId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	var verification TaskVerification
+	if err := json.Unmarshal([]byte(data), &verification); err != nil {
+		return nil, fmt.Errorf("failed to parse verification data: %v", err)
+	}
+
+	task := &Task{TaskId: taskId}
+	switch {
+	case verification.Resolution != nil:
+		task.TaskResult = TaskResult{Operator: verification.Resolution.Operator, Result: verification.Resolution.Result}
+	case verification.Consensus != nil && verification.Performer != nil:
+		task.TaskResult = TaskResult{Operator: verification.Performer.Address, Result: verification.Consensus.Result}
+		task.Weights = verification.Consensus.Weights
+	case verification.Timeout != nil:
+		task.TaskResult = TaskResult{Operator: verification.Timeout.Operator, Result: verification.Timeout.Result}
+		task.Timeout = verification.Timeout.Outcome
+		task.Missing = verification.Timeout.Missing
+	default:
+		return nil, fmt.Errorf("task %d has no final outcome", taskId)
+	}
+	if !SubmittableResult(task.TaskResult.Result) {
+		return nil, ErrResultNotSubmittable
+	}
+
+	b, err := json.Marshal(task)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal task: %v", err)
+	}
+	if err := S.RedisConn.LPush(ctx, PkTaskQueue, b).Err(); err != nil {
+		return nil, fmt.Errorf("failed to queue task: %v", err)
+	}
+	return task, nil
+}
@@ -5,6 +5,15 @@ import "github.com/go-redis/redis/v8"
 type Task struct {
 	TaskId     uint64     `json:"taskID"`
 	TaskResult TaskResult `json:"taskResult"`
+	// Weights are the stakes the attester votes were weighted with, by attester address
+	Weights map[string]string `json:"weights"`
+	// Timeout is the outcome of a task finalized at its deadline without consensus, and Missing
+	// the operators that never responded to it
+	Timeout string   `json:"timeout,omitempty"`
+	Missing []string `json:"missing,omitempty"`
+	// Attempts is the number of failed RespondToTask submissions and LastError the latest failure
+	Attempts  int    `json:"attempts,omitempty"`
+	LastError string `json:"lastError,omitempty"`
 }
 
 type TaskResult struct {
@@ -17,11 +26,69 @@ type TaskSubmission struct {
 	Result    string `json:"result"`
 	Timestamp int64  `json:"timestamp"`
 	Role      string `json:"role"`
+	// Stake is the attester's delegated stake its vote is weighted with, in the epoch StakeEpoch
+	Stake      string `json:"stake,omitempty"`
+	StakeEpoch int64  `json:"stakeEpoch,omitempty"`
+	// TaskType is the network the task targets
+	TaskType string `json:"taskType,omitempty"`
+	// Check is the aggregator's own check of a performer's result, if its policy asks for one
+	Check *PerformerCheck `json:"check,omitempty"`
+}
+
+// PerformerCheck is the aggregator's verdict on a performer's block, read from the trusted
+// RPCs of [verifier].
+type PerformerCheck struct {
+	Verdict   string `json:"verdict"`         // CheckValid, CheckInvalid or CheckError
+	Error     string `json:"error,omitempty"` // why the block is invalid or could not be checked
+	CheckedAt int64  `json:"checkedAt"`
 }
 
 type TaskVerification struct {
 	Performer *TaskSubmission            `json:"performer"`
 	Attesters map[string]*TaskSubmission `json:"attesters"`
+	TaskType  string                     `json:"taskType,omitempty"`
+	// Status is the vote status after the latest submission
+	Status string `json:"status,omitempty"`
+	// Deadline and DeadlineHeight are when the task is finalized without consensus, as unix
+	// seconds and as block height (0 for none)
+	Deadline       int64 `json:"deadline,omitempty"`
+	DeadlineHeight int64 `json:"deadlineHeight,omitempty"`
+	// Split is set while enough attesters and stake voted, but no side reached the threshold
+	Split      bool              `json:"split,omitempty"`
+	Consensus  *ConsensusOutcome `json:"consensus,omitempty"`
+	Timeout    *TaskTimeout      `json:"timeout,omitempty"`
+	Resolution *TaskResolution   `json:"resolution,omitempty"`
+}
+
+// TaskResolution is the result an administrator finalized a task with.
+//
+// Actor is the X-Admin-Actor header of the request, as reported by the client.
+type TaskResolution struct {
+	Result     int64  `json:"result"`
+	Operator   string `json:"operator"`
+	Actor      string `json:"actor"`
+	Reason     string `json:"reason"`
+	ResolvedAt int64  `json:"resolvedAt"`
+}
+
+// TaskTimeout is the outcome of a task finalized at its deadline without consensus.
+type TaskTimeout struct {
+	Outcome   string   `json:"outcome"`
+	Result    int64    `json:"result"`
+	Operator  string   `json:"operator"` // the performer, or the assigned performer if it never submitted
+	ExpiredAt int64    `json:"expiredAt"`
+	Missing   []string `json:"missing"`
+}
+
+// ConsensusOutcome is the outcome of a finished task and the weights that decided it.
+type ConsensusOutcome struct {
+	Result        int64             `json:"result"`
+	PositiveStake string            `json:"positiveStake"`
+	TotalStake    string            `json:"totalStake"`
+	Weights       map[string]string `json:"weights"`
+	Quorum        string            `json:"quorum"`
+	// DecidedBy is "verifier" if the aggregator's check broke a tie or vetoed an approval
+	DecidedBy string `json:"decidedBy,omitempty"`
 }
 
 const (
@@ -31,15 +98,74 @@ const (
 )
 
 type Config struct {
-	App      App
-	Database Database
-	Chain    Chain
-	Owner    Owner
+	App       App
+	Database  Database
+	Chain     Chain
+	Owner     Owner
+	Consensus Consensus
+	Batch     Batch
+	Limits    Limits
+	Admin     Admin
+	Verifier  Verifier
+	Stake     Stake
+}
+
+// Stake configures how the shares of the delegation strategies are converted to one unit of
+// stake. Shares of strategies that are not listed do not count; without any strategy listed all
+// shares are summed as they are, which is only right if all strategies share one unit.
+type Stake struct {
+	Strategies []Strategy `json:"strategies"`
+}
+
+// Strategy converts the shares of one strategy to stake, scaled to StakeDecimals decimals and
+// multiplied by the weight.
+type Strategy struct {
+	Address  string `json:"address"`
+	Decimals int    `json:"decimals"` // decimals of the strategy's shares, at most StakeDecimals
+	Weight   uint64 `json:"weight"`   // stake per whole share, e.g. the token price, 1 if 0
+}
+
+// Verifier configures the trusted RPCs the aggregator checks performer results against.
+type Verifier struct {
+	Networks []Network `json:"networks"`
+}
+
+// Network is the set of trusted RPCs of one task type, i.e. the network a task targets.
+type Network struct {
+	Name      string   `json:"name"` // the task type, "default" for the operators' default network
+	Kind      string   `json:"kind"` // cometbft, evm or bitcoin
+	Endpoints []string `json:"endpoints"`
+	Quorum    int      `json:"quorum"` // endpoints that must agree, 0 for a simple majority
+	MaxLag    int64    `json:"maxLag"` // blocks a performer's block may trail the head
+}
+
+// Admin configures the admin API.
+type Admin struct {
+	Token string `json:"token"` // bearer token of the admin API, empty to disable it
+}
+
+// Limits configures the request size and rate limits of the operator APIs.
+type Limits struct {
+	BodyLimit   int64   `json:"bodyLimit"`   // bytes of a request body, DefaultBodyLimit if 0
+	IpRate      float64 `json:"ipRate"`      // requests per second per client IP, 0 for no limit
+	IpBurst     int     `json:"ipBurst"`     // requests a client IP may burst
+	PubKeyRate  float64 `json:"pubKeyRate"`  // submissions per second per public key, 0 for no limit
+	PubKeyBurst int     `json:"pubKeyBurst"` // submissions a public key may burst
+
+	TrustedProxies []string `json:"trustedProxies"` // proxies whose X-Forwarded-For is used as the client IP
+}
+
+// Batch configures how finalized tasks are grouped into RespondToTask transactions.
+type Batch struct {
+	MaxSize int  `json:"maxSize"` // tasks per transaction, 1 to submit every task on its own
+	Window  uint `json:"window"`  // milliseconds to wait for more tasks after the first one
 }
 type App struct {
 	Env       string
 	Host      string
-	Threshold uint
+	ClockSkew uint   `json:"clockSkew"` // seconds a submission timestamp may differ from the aggregator's clock
+	GrpcHost  string `json:"grpcHost"`  // address of the gRPC API, empty to disable it
+	LeaseTtl  uint   `json:"leaseTtl"`  // seconds the leader lease lasts without renewal
 }
 
 type Database struct {
@@ -49,10 +175,16 @@ type Database struct {
 }
 
 type Chain struct {
-	Id           string `json:"id"`
-	Rpc          string `json:"rpc"`
-	BvsHash      string `json:"bvsHash"`
-	BvsDirectory string `json:"bvsDirectory"`
+	Id                string `json:"id"`
+	Rpc               string `json:"rpc"`
+	BvsHash           string `json:"bvsHash"`
+	BvsDirectory      string `json:"bvsDirectory"`
+	DelegationManager string `json:"delegationManager"`
+	StateBank         string `json:"stateBank"`      // StateBank contract the task networks are read from
+	StateBankStart    int64  `json:"stateBankStart"` // height the StateBank is indexed from on the first run, the latest block if 0
+	// NeutralResults is set once the squaring contract leaves the scores unchanged for
+	// ResultInsufficientAttestations and ResultSplitVote, from version 2.1.0
+	NeutralResults bool `json:"neutralResults"`
 }
 
 type Owner struct {
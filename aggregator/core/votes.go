@@ -0,0 +1,111 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	VoteRecorded = "recorded" // waiting for the performer or more attesters
+	VotePending  = "pending"  // enough votes, but no side reached the consensus threshold yet
+	VoteApproved = "approved" // consensus reached, the performer's result is correct
+	VoteRejected = "rejected" // consensus reached, the performer's result is wrong
+	VoteExpired  = "expired"  // finalized at the deadline without consensus
+	VoteResolved = "resolved" // finalized with a result chosen by an administrator
+)
+
+var (
+	ErrTaskFinished              = errors.New("task already finished")
+	ErrPerformerAlreadySubmitted = errors.New("performer already submitted")
+	ErrAttesterAlreadySubmitted  = errors.New("attester already submitted")
+	ErrTaskTypeMismatch          = errors.New("network differs from the network of the task")
+)
+
+var recordVote = redis.NewScript(RecordVoteScript)
+
+// taskTTL is how long a task's verification data and finished flag are kept.
+const taskTTL = 24 * time.Hour
+
+// VoteOutcome is the state of a task after a submission was recorded.
+type VoteOutcome struct {
+	Status    string
+	Attesters int64
+	// PositiveStake and TotalStake are the weight that voted true and the weight that voted at
+	// all, as decimal strings; the weight is the stake, or one per attester in count quorum
+	PositiveStake string
+	TotalStake    string
+	// Result is the task result queued for the contract, only set if Status is
+	// VoteApproved or VoteRejected.
+	Result int64
+}
+
+// RecordVote atomically records a submission for a task and evaluates stake-weighted consensus.
+//
+// If the policy asks for verification, the performer's Check breaks ties or vetoes approvals.
+// Recording, duplicate detection, consensus evaluation, queueing the task and setting the
+// task_finished flag happen in a single Redis script, so concurrent submissions cannot
+// overwrite each other.
+// ctx is the context for the Redis call.
+// taskId is the unique identifier of the task.
+// submission is the performer's or an attester's verified submission; attesters carry their stake.
+// policy is the consensus policy for the submission's task type.
+// deadline is when the task is finalized without consensus; only the first submission's counts.
+// Returns the outcome, ErrTaskFinished, ErrPerformerAlreadySubmitted/ErrAttesterAlreadySubmitted or
+// ErrTaskTypeMismatch if the submission is rejected, or an error if Redis fails.
+func RecordVote(ctx context.Context, taskId uint64, submission *TaskSubmission, policy ConsensusPolicy, deadline Deadline) (VoteOutcome, error) {
+	submissionStr, err := json.Marshal(submission)
+	if err != nil {
+		return VoteOutcome{}, fmt.Errorf("failed to marshal submission: %v", err)
+	}
+
+	keys := []string{
+		fmt.Sprintf("%s%d", PkTaskVerification, taskId),
+		fmt.Sprintf("%s%d", PkTaskFinished, taskId),
+		PkTaskQueue,
+		PkTaskDeadlines,
+		PkTaskDeadlineHeights,
+		PkActiveOperators,
+	}
+	keys = append(keys, historyKeys(taskId)...)
+	res, err := recordVote.Run(ctx, S.RedisConn, keys,
+		taskId, submission.Role, submission.Address, submissionStr,
+		policy.MinimumAttesters, policy.Threshold, int64(taskTTL.Seconds()), policy.MinimumStake,
+		policy.Quorum, policy.Tie, submission.TaskType, deadline.Time.Unix(), deadline.Height,
+		policy.Verification,
+	).Slice()
+	if err != nil {
+		return VoteOutcome{}, fmt.Errorf("failed to record vote: %v", err)
+	}
+	if len(res) < 5 {
+		return VoteOutcome{}, fmt.Errorf("unexpected vote script reply: %v", res)
+	}
+
+	status, _ := res[0].(string)
+	attesters, _ := res[1].(int64)
+	positive, _ := res[2].(string)
+	total, _ := res[3].(string)
+	result, _ := res[4].(int64)
+	switch status {
+	case "finished":
+		return VoteOutcome{}, ErrTaskFinished
+	case "duplicate":
+		if submission.Role == RolePerformer {
+			return VoteOutcome{}, ErrPerformerAlreadySubmitted
+		}
+		return VoteOutcome{}, ErrAttesterAlreadySubmitted
+	case "mismatch":
+		return VoteOutcome{}, ErrTaskTypeMismatch
+	case VoteApproved, VoteRejected:
+		if len(res) != 6 {
+			return VoteOutcome{}, fmt.Errorf("unexpected vote script reply: %v", res)
+		}
+		verificationStr, _ := res[5].(string)
+		archiveOperators(ctx, taskId, verificationStr, result, nil)
+	}
+	return VoteOutcome{Status: status, Attesters: attesters, PositiveStake: positive, TotalStake: total, Result: result}, nil
+}
@@ -3,8 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
 
 	"github.com/satlayer/hello-world-bvs/aggregator/api"
 	"github.com/satlayer/hello-world-bvs/aggregator/core"
@@ -13,15 +20,28 @@ import (
 
 // main is the entry point of the program.
 //
-// It initializes a background context and starts two goroutines:
-// - startMonitor: checks the task queue and verifies the task result.
+// It initializes a context that is cancelled on SIGINT or SIGTERM and starts:
+// - core.WatchConsensus: reloads the consensus policy when env.toml changes.
+// - startGrpc: starts the gRPC server if [app] grpcHost is set.
 // - startHttp: starts an HTTP server to receive operator task results.
+// - startLeader: competes for the leader lease and, while elected, runs the monitor and the sweeper.
+// Every replica serves the APIs, only the leader submits task results.
 func main() {
-	ctx := context.Background()
-	// start to check task queue and verify the task result
-	go startMonitor(ctx)
-	// start http server to receive operator task result
-	startHttp()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// hot reload the [consensus] section
+	go core.WatchConsensus(ctx, 5*time.Second)
+	// start grpc server to receive operator task result
+	if core.C.App.GrpcHost != "" {
+		go startGrpc()
+	}
+	// start http server to receive operator task result, and stop if it fails
+	go func() {
+		startHttp()
+		stop()
+	}()
+	// deliver task results while this replica is the leader, until stopped
+	startLeader(ctx)
 }
 
 // startHttp starts an HTTP server to receive operator task results.
@@ -30,6 +50,11 @@ func main() {
 // Returns no value.
 func startHttp() {
 	router := gin.Default()
+	// the client IP is rate limited, so only trust X-Forwarded-For from configured proxies
+	if err := router.SetTrustedProxies(core.C.Limits.TrustedProxies); err != nil {
+		core.L.Error(fmt.Sprintf("Invalid trusted proxies due to {%s}", err))
+		return
+	}
 	// setup routes
 	api.SetupRoutes(router)
 	// start server
@@ -39,6 +64,47 @@ func startHttp() {
 	}
 }
 
+// startGrpc starts a gRPC server to receive operator task results.
+//
+// It registers the Aggregator service and serves it at the specified gRPC host, with the
+// [limits] applied.
+// Returns no value.
+func startGrpc() {
+	listener, err := net.Listen("tcp", core.C.App.GrpcHost)
+	if err != nil {
+		core.L.Error(fmt.Sprintf("Failed to listen for gRPC due to {%s}", err))
+		return
+	}
+	server := grpc.NewServer(api.GRPCOptions()...)
+	api.RegisterGRPC(server)
+	core.L.Info(fmt.Sprintf("Start gRPC server at {%s}", core.C.App.GrpcHost))
+	if err := server.Serve(listener); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to start gRPC server due to {%s}", err))
+	}
+}
+
+// startLeader runs the monitor and the sweeper while this replica holds the leader lease.
+//
+// It returns once ctx is done and the lease is released.
+// No return value.
+func startLeader(ctx context.Context) {
+	svc.RunElected(ctx, svc.InstanceId, svc.LeaseTTL(), func(ctx context.Context) {
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			// check task queue and submit the task results
+			startMonitor(ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			// finalize expired tasks
+			startSweeper(ctx)
+		}()
+		wg.Wait()
+	})
+}
+
 // startMonitor starts the task queue monitor.
 //
 // It initializes a new monitor and runs it with the provided context.
@@ -46,3 +112,11 @@ func startHttp() {
 func startMonitor(ctx context.Context) {
 	svc.MONITOR.Run(ctx)
 }
+
+// startSweeper starts finalizing tasks whose deadline passed without consensus.
+//
+// It runs the monitor's sweeper with the provided context.
+// No return value.
+func startSweeper(ctx context.Context) {
+	svc.MONITOR.SweepExpiredTasks(ctx, core.SweepInterval)
+}
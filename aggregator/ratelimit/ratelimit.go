@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// minIdle is the shortest time an unused bucket is kept before it is dropped.
+const minIdle = time.Minute
+
+// Limiter keeps a token bucket per key, e.g. per client IP or per public key.
+//
+// Buckets that were not used for longer than they take to refill are dropped, so the number
+// of buckets is bounded by the keys seen recently.
+type Limiter struct {
+	limit rate.Limit
+	burst int
+	idle  time.Duration
+
+	// Now returns the current time, replaceable in tests
+	Now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// New creates a Limiter allowing perSecond requests per key on average, and bursts of burst.
+//
+// A perSecond of 0 or less disables the limit. A burst below 1 is raised to 1.
+func New(perSecond float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	idle := minIdle
+	if perSecond > 0 {
+		if refill := time.Duration(float64(burst) / perSecond * float64(time.Second)); refill > idle {
+			idle = refill
+		}
+	}
+	return &Limiter{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		idle:    idle,
+		Now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Enabled reports whether the Limiter limits anything.
+func (l *Limiter) Enabled() bool {
+	return l.limit > 0
+}
+
+// Allow takes a token from the bucket of key.
+//
+// Returns false if the bucket is empty.
+func (l *Limiter) Allow(key string) bool {
+	if !l.Enabled() {
+		return true
+	}
+	now := l.Now()
+	return l.bucket(key, now).AllowN(now, 1)
+}
+
+// Reservation is a token taken from a bucket, see Reserve.
+type Reservation struct {
+	r   *rate.Reservation
+	now func() time.Time
+}
+
+// Cancel returns the token to its bucket, e.g. because the request turned out to be forged.
+func (r *Reservation) Cancel() {
+	if r != nil && r.r != nil {
+		r.r.CancelAt(r.now())
+	}
+}
+
+// Reserve takes a token from the bucket of key that can be returned with Cancel.
+//
+// Returns false if the bucket is empty.
+func (l *Limiter) Reserve(key string) (*Reservation, bool) {
+	if !l.Enabled() {
+		return &Reservation{}, true
+	}
+	now := l.Now()
+	r := l.bucket(key, now).ReserveN(now, 1)
+	if !r.OK() {
+		return nil, false
+	}
+	if r.DelayFrom(now) > 0 {
+		r.CancelAt(now)
+		return nil, false
+	}
+	return &Reservation{r: r, now: l.Now}, true
+}
+
+// Len returns the number of buckets kept.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// bucket returns the bucket of key, creating it if needed, and drops idle buckets.
+func (l *Limiter) bucket(key string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > l.idle {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastSeen) > l.idle {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[key] = b
+	}
+	b.lastSeen = now
+	return b.limiter
+}
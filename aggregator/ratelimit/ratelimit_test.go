@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := New(1, 2)
+	l.Now = func() time.Time { return now }
+
+	if !l.Allow("a") || !l.Allow("a") {
+		t.Fatal("expected the burst to be allowed")
+	}
+	if l.Allow("a") {
+		t.Fatal("expected the empty bucket to reject")
+	}
+	// buckets are per key
+	if !l.Allow("b") {
+		t.Fatal("expected another key to be allowed")
+	}
+
+	now = now.Add(time.Second)
+	if !l.Allow("a") {
+		t.Fatal("expected a refilled token to be allowed")
+	}
+	if l.Allow("a") {
+		t.Fatal("expected only one token to be refilled")
+	}
+}
+
+func TestReserveCancel(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := New(1, 1)
+	l.Now = func() time.Time { return now }
+
+	r, ok := l.Reserve("a")
+	if !ok {
+		t.Fatal("expected the first reservation to succeed")
+	}
+	if _, ok := l.Reserve("a"); ok {
+		t.Fatal("expected the empty bucket to reject")
+	}
+
+	// a cancelled reservation gives its token back
+	r.Cancel()
+	if _, ok := l.Reserve("a"); !ok {
+		t.Fatal("expected the returned token to be reserved again")
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	l := New(0, 0)
+	for i := 0; i < 100; i++ {
+		if !l.Allow("a") {
+			t.Fatal("expected a disabled limiter to allow everything")
+		}
+		r, ok := l.Reserve("a")
+		if !ok {
+			t.Fatal("expected a disabled limiter to reserve everything")
+		}
+		r.Cancel()
+	}
+	if l.Len() != 0 {
+		t.Fatalf("expected a disabled limiter to keep no buckets, got %d", l.Len())
+	}
+}
+
+func TestIdleBucketsDropped(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := New(10, 10)
+	l.Now = func() time.Time { return now }
+
+	l.Allow("a")
+	l.Allow("b")
+	if l.Len() != 2 {
+		t.Fatalf("expected 2 buckets, got %d", l.Len())
+	}
+
+	now = now.Add(2 * minIdle)
+	l.Allow("c")
+	if l.Len() != 1 {
+		t.Fatalf("expected idle buckets to be dropped, got %d buckets", l.Len())
+	}
+}
@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PkOperatorNonce is the key prefix of the last nonce accepted from an operator.
+const PkOperatorNonce = "operator_nonce:"
+
+// DefaultClockSkew is the clock skew tolerated when none is configured.
+const DefaultClockSkew = 2 * time.Minute
+
+var (
+	ErrTimestampOutOfRange = errors.New("timestamp out of range")
+	ErrNonceReused         = errors.New("nonce already used")
+)
+
+// advanceNonce sets KEYS[1] to ARGV[1] only if ARGV[1] is greater than the stored nonce.
+// Nonces are compared as decimal strings of equal length to stay exact beyond 2^53.
+const advanceNonce = `
+	local last = redis.call("GET", KEYS[1]);
+	if last then
+		local next = ARGV[1];
+		if #next < #last or (#next == #last and next <= last) then
+			return 0;
+		end
+	end
+	redis.call("SET", KEYS[1], ARGV[1]);
+	return 1;
+`
+
+// NonceStore keeps the last nonce accepted from each operator.
+type NonceStore interface {
+	// Advance records nonce for operator if it is greater than the last one.
+	// Returns false if the nonce is not greater, i.e. it was reused or replayed.
+	Advance(ctx context.Context, operator string, nonce uint64) (bool, error)
+}
+
+// RedisNonceStore is a NonceStore shared by every aggregator using the same Redis.
+type RedisNonceStore struct {
+	conn   *redis.Client
+	script *redis.Script
+}
+
+// NewRedisNonceStore creates a NonceStore on conn.
+func NewRedisNonceStore(conn *redis.Client) *RedisNonceStore {
+	return &RedisNonceStore{conn: conn, script: redis.NewScript(advanceNonce)}
+}
+
+func (s *RedisNonceStore) Advance(ctx context.Context, operator string, nonce uint64) (bool, error) {
+	key := PkOperatorNonce + operator
+	ok, err := s.script.Run(ctx, s.conn, []string{key}, fmt.Sprintf("%d", nonce)).Int()
+	if err != nil {
+		return false, err
+	}
+	return ok == 1, nil
+}
+
+// MemoryNonceStore is a NonceStore local to the process.
+type MemoryNonceStore struct {
+	mu   sync.Mutex
+	last map[string]uint64
+}
+
+// NewMemoryNonceStore creates an empty MemoryNonceStore.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{last: make(map[string]uint64)}
+}
+
+func (s *MemoryNonceStore) Advance(ctx context.Context, operator string, nonce uint64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.last[operator]; ok && nonce <= last {
+		return false, nil
+	}
+	s.last[operator] = nonce
+	return true, nil
+}
+
+// Guard rejects stale and replayed operator submissions.
+type Guard struct {
+	// Now returns the current time; tests replace it to control the clock.
+	Now       func() time.Time
+	ClockSkew time.Duration
+	Nonces    NonceStore
+}
+
+// NewGuard creates a Guard on the wall clock.
+//
+// nonces is where accepted nonces are tracked.
+// clockSkew is how far a submission timestamp may be from the aggregator's clock, in either
+// direction; 0 means DefaultClockSkew.
+// Returns the guard.
+func NewGuard(nonces NonceStore, clockSkew time.Duration) *Guard {
+	if clockSkew <= 0 {
+		clockSkew = DefaultClockSkew
+	}
+	return &Guard{Now: time.Now, ClockSkew: clockSkew, Nonces: nonces}
+}
+
+// CheckTimestamp checks that timestamp, in Unix seconds, is within the clock-skew window.
+func (g *Guard) CheckTimestamp(timestamp int64) error {
+	submitted := time.Unix(timestamp, 0)
+	now := g.Now()
+	if submitted.Before(now.Add(-g.ClockSkew)) || submitted.After(now.Add(g.ClockSkew)) {
+		return ErrTimestampOutOfRange
+	}
+	return nil
+}
+
+// Accept consumes nonce for operator.
+//
+// Nonces must strictly increase per operator, across all tasks, so every signed submission
+// can be accepted at most once.
+// ctx is the context for the store.
+// operator is the address of the submitting operator.
+// nonce is the nonce of the submission.
+// Returns ErrNonceReused if the nonce is not greater than the last accepted one, or the
+// store's error.
+func (g *Guard) Accept(ctx context.Context, operator string, nonce uint64) error {
+	ok, err := g.Nonces.Advance(ctx, operator, nonce)
+	if err != nil {
+		return fmt.Errorf("failed to check nonce: %v", err)
+	}
+	if !ok {
+		return ErrNonceReused
+	}
+	return nil
+}
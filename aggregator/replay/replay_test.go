@@ -0,0 +1,72 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCheckTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	g := NewGuard(NewMemoryNonceStore(), 30*time.Second)
+	g.Now = func() time.Time { return now }
+
+	cases := []struct {
+		name      string
+		timestamp int64
+		ok        bool
+	}{
+		{"now", now.Unix(), true},
+		{"oldest allowed", now.Unix() - 30, true},
+		{"too old", now.Unix() - 31, false},
+		{"newest allowed", now.Unix() + 30, true},
+		{"too far ahead", now.Unix() + 31, false},
+	}
+	for _, c := range cases {
+		err := g.CheckTimestamp(c.timestamp)
+		if c.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		}
+		if !c.ok && !errors.Is(err, ErrTimestampOutOfRange) {
+			t.Errorf("%s: expected ErrTimestampOutOfRange, got %v", c.name, err)
+		}
+	}
+
+	// a submission that was fresh becomes stale once the clock moves on
+	timestamp := now.Unix()
+	now = now.Add(time.Minute)
+	if err := g.CheckTimestamp(timestamp); !errors.Is(err, ErrTimestampOutOfRange) {
+		t.Errorf("expected stale timestamp to be rejected, got %v", err)
+	}
+}
+
+func TestDefaultClockSkew(t *testing.T) {
+	g := NewGuard(NewMemoryNonceStore(), 0)
+	if g.ClockSkew != DefaultClockSkew {
+		t.Fatalf("expected default clock skew %s, got %s", DefaultClockSkew, g.ClockSkew)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	ctx := context.Background()
+	g := NewGuard(NewMemoryNonceStore(), 0)
+
+	steps := []struct {
+		operator string
+		nonce    uint64
+		err      error
+	}{
+		{"op1", 10, nil},
+		{"op1", 10, ErrNonceReused}, // replay of the same submission
+		{"op1", 9, ErrNonceReused},  // older submission, e.g. for another task
+		{"op1", 11, nil},
+		{"op2", 10, nil}, // nonces are tracked per operator
+		{"op2", 1, ErrNonceReused},
+	}
+	for i, s := range steps {
+		if err := g.Accept(ctx, s.operator, s.nonce); !errors.Is(err, s.err) {
+			t.Errorf("step %d: expected %v, got %v", i, s.err, err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	BvsSquaringApi "github.com/satlayer/hello-world-bvs/bvs_squaring_api"
+)
+
+// ErrUnknownTask is returned when the squaring contract has no task with the given id.
+var ErrUnknownTask = errors.New("unknown task")
+
+// AssignedPerformer returns the operator CreateNewTask assigned to perform a task.
+//
+// The assignment is read from the squaring contract's GetTaskInput, which holds the same
+// address the contract writes to the StateBank key "taskId.<id>". It never changes, so it
+// is cached in Redis per task.
+// ctx is the context for the cache.
+// taskId is the unique identifier of the task.
+// Returns the performer address, ErrUnknownTask if the task does not exist, or an error if
+// the assignment could not be read.
+func (m *Monitor) AssignedPerformer(ctx context.Context, taskId uint64) (string, error) {
+	key := fmt.Sprintf("%s%d", core.PkTaskAssignment, taskId)
+	performer, err := core.S.RedisConn.Get(ctx, key).Result()
+	if err == nil {
+		return performer, nil
+	}
+	if err != redis.Nil {
+		return "", fmt.Errorf("failed to read task assignment: %v", err)
+	}
+
+	bvsSquaring := BvsSquaringApi.NewBVSSquaring(m.chainIO)
+	bvsSquaring.BindClient(m.bvsContract)
+	resp, err := bvsSquaring.GetTaskInput(int64(taskId))
+	if err != nil {
+		if strings.Contains(err.Error(), "no value found") {
+			return "", ErrUnknownTask
+		}
+		return "", fmt.Errorf("failed to query task input: %v", err)
+	}
+	if err := json.Unmarshal(resp.Data, &performer); err != nil {
+		return "", fmt.Errorf("failed to parse task input: %v", err)
+	}
+
+	if err := core.S.RedisConn.Set(ctx, key, performer, 24*time.Hour).Err(); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to cache task assignment, due to {%s}", err))
+	}
+	return performer, nil
+}
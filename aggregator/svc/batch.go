@@ -0,0 +1,228 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satlayer/satlayer-api/chainio/types"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	BvsSquaringApi "github.com/satlayer/hello-world-bvs/bvs_squaring_api"
+)
+
+// broadcastTTL is how long a transaction may be in flight after holdsLease let it through: it
+// may wait TxTimeout for the sequence of an unconfirmed transaction, then TxTimeout to be
+// included, see BvsSquaringApi.RespondToTasks.
+const broadcastTTL = 2*BvsSquaringApi.TxTimeout + 10*time.Second
+
+// queuedTask is a task taken from the queue, with the raw item it is kept under while processing.
+type queuedTask struct {
+	item string
+	task core.Task
+}
+
+// nextBatch waits for a finalized task and collects further ones for up to the batch window.
+//
+// Items that cannot be parsed are dead-lettered right away.
+// ctx is the context for the Redis calls.
+// Returns the tasks, which are in the processing list, no tasks if none was queued within
+// core.QueuePollTimeout, or an error if Redis fails.
+func (m *Monitor) nextBatch(ctx context.Context) ([]queuedTask, error) {
+	maxSize := core.C.Batch.MaxSize
+	if maxSize < 1 {
+		maxSize = 1
+	}
+
+	// wake up regularly, so the monitor notices when it has to stop
+	item, err := core.NextTask(ctx, core.QueuePollTimeout)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var batch []queuedTask
+	batch = m.appendTask(ctx, batch, item)
+
+	deadline := time.Now().Add(time.Duration(core.C.Batch.Window) * time.Millisecond)
+	for len(batch) < maxSize && time.Now().Before(deadline) {
+		item, err := core.TryNextTask(ctx)
+		if err == redis.Nil {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		if err != nil {
+			return batch, err
+		}
+		batch = m.appendTask(ctx, batch, item)
+	}
+	return batch, nil
+}
+
+func (m *Monitor) appendTask(ctx context.Context, batch []queuedTask, item string) []queuedTask {
+	fmt.Printf("result--->: %s\n", item)
+	task := core.Task{}
+	if err := json.Unmarshal([]byte(item), &task); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to parse task queue, due to {%s}", err))
+		m.failTask(ctx, item, nil, err)
+		return batch
+	}
+	fmt.Printf("task: %+v\n", task)
+	if !core.SubmittableResult(task.TaskResult.Result) {
+		// queued by an older aggregator or with [chain] neutralResults set, its outcome stays in Redis
+		core.L.Info(fmt.Sprintf("Task {%d} is not submitted, the squaring contract does not accept result {%d}", task.TaskId, task.TaskResult.Result))
+		m.ackTask(ctx, queuedTask{item: item, task: task})
+		return batch
+	}
+	// consensus was already reached under the task's policy, or the task expired, when it was queued
+	if task.Timeout != "" {
+		core.L.Info(fmt.Sprintf("Task {%d} timed out: {%s}. The result is {%d}. The operator is {%s}. Missing operators: {%v}", task.TaskId, task.Timeout, task.TaskResult.Result, task.TaskResult.Operator, task.Missing))
+	} else {
+		core.L.Info(fmt.Sprintf("Task {%d} is finished. The result is {%d}. The operator is {%s}. The weights are {%v}", task.TaskId, task.TaskResult.Result, task.TaskResult.Operator, task.Weights))
+	}
+	if err := core.TrackDeliveries(ctx, []uint64{task.TaskId}, time.Now()); err != nil {
+		// reconciliation still checks the task while it is pending
+		core.L.Error(fmt.Sprintf("Failed to track delivery of task {%d}, due to {%s}", task.TaskId, err))
+	}
+	return append(batch, queuedTask{item: item, task: task})
+}
+
+// deliverBatch submits a batch of tasks in one multi-message transaction.
+//
+// If the transaction fails because of one message, that task is retried on its own and the
+// rest of the batch is submitted again. If the failed message is unknown, every task is
+// submitted on its own, so one bad task cannot hold back the others.
+// Nothing is submitted once the leader lease is lost, see holdsLease.
+func (m *Monitor) deliverBatch(ctx context.Context, batch []queuedTask) {
+	if !m.holdsLease(ctx, batch) {
+		return
+	}
+	if len(batch) == 1 {
+		m.deliverTask(ctx, batch[0])
+		return
+	}
+
+	responses := make([]types.RespondToTask, len(batch))
+	for i, queued := range batch {
+		responses[i] = types.RespondToTask{
+			TaskId:    int64(queued.task.TaskId),
+			Result:    queued.task.TaskResult.Result,
+			Operators: queued.task.TaskResult.Operator,
+		}
+	}
+	core.L.Info(fmt.Sprintf("Sending a batch of {%d} task results", len(responses)))
+	bvsSquaring := BvsSquaringApi.NewBVSSquaring(m.chainIO)
+	bvsSquaring.BindClient(m.bvsContract)
+	res, err := bvsSquaring.RespondToTasks(ctx, responses)
+	m.endBroadcast(ctx, err)
+	if err == nil {
+		for _, queued := range batch {
+			core.L.Info(fmt.Sprintf("Task {%d} responded in tx {%X}", queued.task.TaskId, res.Hash))
+			m.ackTask(ctx, queued)
+		}
+		return
+	}
+
+	core.L.Error(fmt.Sprintf("Failed to send a batch of {%d} task results, due to {%s}", len(batch), err))
+	var txErr *BvsSquaringApi.TxError
+	if errors.As(err, &txErr) && txErr.MsgIndex >= 0 && txErr.MsgIndex < len(batch) {
+		i := txErr.MsgIndex
+		if isResultSubmitted(err) {
+			// an earlier submission of the task landed
+			m.ackTask(ctx, batch[i])
+		} else {
+			m.failTask(ctx, batch[i].item, &batch[i].task, err)
+		}
+		rest := append(append([]queuedTask{}, batch[:i]...), batch[i+1:]...)
+		m.deliverBatch(ctx, rest)
+		return
+	}
+	for _, queued := range batch {
+		m.deliverTask(ctx, queued)
+	}
+}
+
+// deliverTask submits a single task in its own transaction.
+func (m *Monitor) deliverTask(ctx context.Context, queued queuedTask) {
+	if !m.holdsLease(ctx, []queuedTask{queued}) {
+		return
+	}
+	err := m.sendTaskResult(ctx, queued.task.TaskId, queued.task.TaskResult.Result, queued.task.TaskResult.Operator)
+	m.endBroadcast(ctx, err)
+	if isResultSubmitted(err) {
+		core.L.Info(fmt.Sprintf("Task {%d} was already responded to", queued.task.TaskId))
+		err = nil
+	}
+	if err != nil {
+		core.L.Error(fmt.Sprintf("Failed to send task result, due to {%s}", err))
+		m.failTask(ctx, queued.item, &queued.task, err)
+		return
+	}
+	m.ackTask(ctx, queued)
+}
+
+// holdsLease checks the fencing token of ctx before a transaction is broadcast, and marks the
+// broadcast as in flight for broadcastTTL, see core.BeginBroadcast.
+//
+// If the lease was lost, the tasks are returned to the queue for the new leader and false is
+// returned. A ctx without a fencing token always holds the lease.
+// The check does not fence the broadcast itself: a leader that loses its lease right after the
+// check still broadcasts, and a transaction that is not included yet cannot be called back. A
+// new leader therefore waits until the mark was cleared or expired before it submits anything,
+// see Monitor.Run, so the transactions of two leaders never compete for the account sequence.
+// A task both leaders submit lands once, the squaring contract rejects the second response.
+func (m *Monitor) holdsLease(ctx context.Context, batch []queuedTask) bool {
+	token, ok := core.Fence(ctx)
+	if !ok {
+		return true
+	}
+	err := ctx.Err()
+	if err == nil {
+		err = core.BeginBroadcast(ctx, token, broadcastTTL)
+	}
+	if err == nil {
+		return true
+	}
+	core.L.Error(fmt.Sprintf("Not submitting {%d} tasks with fencing token {%d}, due to {%s}", len(batch), token, err))
+	items := make([]string, len(batch))
+	for i, queued := range batch {
+		items[i] = queued.item
+	}
+	// ctx may be cancelled already, the tasks must still be returned
+	if _, err := core.ReturnTasks(context.Background(), items); err != nil {
+		// the tasks stay in the processing list until the next leader recovers them
+		core.L.Error(fmt.Sprintf("Failed to return tasks to the queue, due to {%s}", err))
+	}
+	return false
+}
+
+// endBroadcast clears the mark of holdsLease once the transaction was included or failed.
+//
+// A transaction that was not confirmed may still be included, so its mark is left to expire.
+func (m *Monitor) endBroadcast(ctx context.Context, err error) {
+	token, ok := core.Fence(ctx)
+	if !ok || errors.Is(err, BvsSquaringApi.ErrTxNotConfirmed) {
+		return
+	}
+	// ctx may be cancelled already, the mark must still be cleared
+	if err := core.EndBroadcast(context.Background(), token); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to clear broadcast of fencing token {%d}, due to {%s}", token, err))
+	}
+}
+
+// ackTask removes a task whose result landed, or that is not submitted, from the processing
+// list and marks it as confirmed for reconciliation.
+func (m *Monitor) ackTask(ctx context.Context, queued queuedTask) {
+	if err := core.AckTask(ctx, queued.item); err != nil {
+		// the task is recovered and submitted again on the next start
+		core.L.Error(fmt.Sprintf("Failed to ack task {%d}, due to {%s}", queued.task.TaskId, err))
+	}
+	if err := core.ConfirmTasks(ctx, queued.task.TaskId); err != nil {
+		// the next reconciliation finds the result on chain
+		core.L.Error(fmt.Sprintf("Failed to confirm task {%d}, due to {%s}", queued.task.TaskId, err))
+	}
+}
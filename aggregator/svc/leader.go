@@ -0,0 +1,117 @@
+package svc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// InstanceId identifies this replica in the leader lease.
+var InstanceId = newInstanceId()
+
+// newInstanceId returns an id unique to this process, readable in the logs.
+func newInstanceId() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "aggregator"
+	}
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// LeaseTTL returns how long the leader lease lasts without renewal, from [app] leaseTtl.
+func LeaseTTL() time.Duration {
+	if core.C.App.LeaseTtl == 0 {
+		return core.DefaultLeaseTTL
+	}
+	return time.Duration(core.C.App.LeaseTtl) * time.Second
+}
+
+// RunElected runs run while this replica holds the leader lease, until ctx is done.
+//
+// Every replica competes for the lease every ttl/3. The leader renews it at the same interval
+// and runs run with a context carrying its fencing token, see core.WithFence. The context is
+// cancelled when the lease is lost: when another replica holds it, or when it could not be
+// renewed before it expired. The lease is released once run returned, so another replica
+// takes over right away on shutdown.
+// ctx is the context of the replica.
+// holder identifies the replica, see InstanceId.
+// ttl is how long the lease lasts without renewal.
+// run is the work only the leader may do.
+// No return values.
+func RunElected(ctx context.Context, holder string, ttl time.Duration, run func(ctx context.Context)) {
+	interval := ttl / 3
+	for ctx.Err() == nil {
+		acquiredAt := time.Now()
+		token, err := core.AcquireLease(ctx, holder, ttl)
+		if err != nil {
+			core.L.Error(fmt.Sprintf("Failed to acquire leader lease, due to {%s}", err))
+		} else if token > 0 {
+			core.L.Info(fmt.Sprintf("Elected leader {%s} with fencing token {%d}", holder, token))
+			lead(ctx, holder, ttl, token, acquiredAt.Add(ttl), run)
+			core.L.Info(fmt.Sprintf("Leader {%s} stepped down", holder))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// lead runs run and renews the lease until ctx is done, run returns or the lease is lost.
+func lead(ctx context.Context, holder string, ttl time.Duration, token int64, validUntil time.Time, run func(ctx context.Context)) {
+	interval := ttl / 3
+	leaderCtx, cancel := context.WithCancel(core.WithFence(ctx, token))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(leaderCtx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+		// ctx may be done already, the release must still reach Redis
+		releaseCtx, cancelRelease := context.WithTimeout(context.Background(), interval)
+		defer cancelRelease()
+		if err := core.ReleaseLease(releaseCtx, holder); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to release leader lease, due to {%s}", err))
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		renewedAt := time.Now()
+		renewCtx, cancelRenew := context.WithTimeout(ctx, interval)
+		renewed, err := core.AcquireLease(renewCtx, holder, ttl)
+		cancelRenew()
+		switch {
+		case err == nil && renewed == token:
+			validUntil = renewedAt.Add(ttl)
+		case err == nil:
+			// the lease expired and is held by another replica, or by us with a newer token
+			core.L.Error(fmt.Sprintf("Leader lease with fencing token {%d} was lost", token))
+			return
+		default:
+			core.L.Error(fmt.Sprintf("Failed to renew leader lease, due to {%s}", err))
+			// step down while the lease is still ours, before another replica can take over
+			if time.Now().Add(interval).After(validUntil) {
+				return
+			}
+		}
+	}
+}
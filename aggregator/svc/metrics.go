@@ -0,0 +1,49 @@
+package svc
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Registry is the registry of the aggregator's Prometheus metrics, served on /metrics.
+var Registry = prometheus.NewRegistry()
+
+// Metrics are the aggregator's Prometheus metrics.
+type Metrics struct {
+	RegistrationCacheHits   prometheus.Counter
+	RegistrationCacheMisses prometheus.Counter
+	RejectedRequests        *prometheus.CounterVec
+	PerformerChecks         *prometheus.CounterVec
+}
+
+// METRICS are the aggregator metrics, registered with Registry.
+var METRICS = NewMetrics(Registry)
+
+// NewMetrics creates the aggregator metrics and registers them with reg.
+//
+// reg is the registry served on /metrics.
+// Returns the metrics.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	const namespace, subsystem = "bvs_demo", "aggregator"
+	m := &Metrics{
+		RegistrationCacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "registration_cache_hits_total",
+			Help: "Operator registration lookups answered from the cache.",
+		}),
+		RegistrationCacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "registration_cache_misses_total",
+			Help: "Operator registration lookups that queried the BVS directory.",
+		}),
+		RejectedRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "rejected_requests_total",
+			Help: "Requests rejected by the size and rate limits, by reason.",
+		}, []string{"reason"}),
+		PerformerChecks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "performer_checks_total",
+			Help: "Performer results checked against the trusted RPCs, by verdict.",
+		}, []string{"verdict"}),
+	}
+	reg.MustRegister(m.RegistrationCacheHits, m.RegistrationCacheMisses, m.RejectedRequests, m.PerformerChecks)
+	return m
+}
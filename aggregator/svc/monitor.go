@@ -2,14 +2,11 @@ package svc
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"runtime"
-	"strings"
 	"time"
 
-	"github.com/prometheus/client_golang/prometheus"
 	"github.com/satlayer/satlayer-api/chainio/types"
 	"github.com/satlayer/satlayer-api/logger"
 	transactionprocess "github.com/satlayer/satlayer-api/metrics/indicators/transaction_process"
@@ -30,6 +27,7 @@ func init() {
 type Monitor struct {
 	bvsContract     string
 	bvsDirectoryApi api.BVSDirectory
+	delegation      api.Delegation
 	chainIO         io.ChainIO
 }
 
@@ -48,8 +46,7 @@ func NewMonitor() *Monitor {
 	fmt.Printf("homeDir: %s\n", homeDir)
 	elkLogger := logger.NewELKLogger("bvs_demo")
 	elkLogger.SetLogLevel("info")
-	reg := prometheus.NewRegistry()
-	metricsIndicators := transactionprocess.NewPromIndicators(reg, "bvs_demo")
+	metricsIndicators := transactionprocess.NewPromIndicators(Registry, "bvs_demo")
 	chainIO, err := io.NewChainIO(core.C.Chain.Id, core.C.Chain.Rpc, homeDir, core.C.Owner.Bech32Prefix, elkLogger, metricsIndicators, types.TxManagerParams{
 		MaxRetries:             5,
 		RetryInterval:          3 * time.Second,
@@ -75,124 +72,116 @@ func NewMonitor() *Monitor {
 	}
 	bvsDirectoryApi := api.NewBVSDirectoryImpl(chainIO, core.C.Chain.BvsDirectory)
 
+	delegation := api.NewDelegationImpl(chainIO, core.C.Chain.DelegationManager)
+
 	return &Monitor{
 		bvsContract:     txResp.BVSContract,
 		bvsDirectoryApi: bvsDirectoryApi,
+		delegation:      delegation,
 		chainIO:         chainIO,
 	}
 }
 
 // Run starts the task queue monitoring process.
 //
+// Tasks are delivered at least once: a task stays in the processing list until RespondToTask
+// succeeded, failed submissions are retried with exponential backoff and dead-lettered after
+// core.MaxDeliveryAttempts. Once no transaction of a previous leader may be in flight, see
+// core.AwaitBroadcast, the tasks it left in the processing list are recovered first,
+// and the pending and unconfirmed tasks are reconciled with the results on chain at start and
+// every core.ReconcileInterval.
+// The operator registration cache is warmed at start and kept up to date from registration events,
+// and the task networks are cached from StateBank events.
+// Tasks finalized within the [batch] window are submitted together in one transaction.
+// Only the elected leader may run it, see RunElected; it returns once ctx is done.
 // It takes a context.Context object as a parameter.
 // No return values.
 func (m *Monitor) Run(ctx context.Context) {
 	core.L.Info("Start to monitor task queue")
-	for {
-		results, err := core.S.RedisConn.BLPop(context.Background(), 0, core.PkTaskQueue).Result()
-		fmt.Printf("results: %+v\n", results)
+	// a previous leader may still have a transaction in flight, see holdsLease
+	if waited, err := core.AwaitBroadcast(ctx); ctx.Err() != nil {
+		return
+	} else if err != nil {
+		core.L.Error(fmt.Sprintf("Failed to wait for the broadcast of the previous leader, due to {%s}", err))
+	} else if waited > time.Second {
+		core.L.Info(fmt.Sprintf("Waited {%s} for the transaction of the previous leader", waited))
+	}
+	if n, err := core.RecoverProcessingTasks(ctx); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to recover processing tasks, due to {%s}", err))
+	} else if n > 0 {
+		core.L.Info(fmt.Sprintf("Recovered {%d} tasks from an interrupted run", n))
+	}
+	if err := m.Reconcile(ctx); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to reconcile tasks, due to {%s}", err))
+	}
+	if refreshed, registered, err := m.RefreshOperators(ctx); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to warm operator registrations, due to {%s}", err))
+	} else {
+		core.L.Info(fmt.Sprintf("Cached registrations of {%d} operators, {%d} registered", refreshed, registered))
+	}
+	go m.WatchRegistrations(ctx)
+	go m.WatchStateBank(ctx)
+	go m.requeueRetries(ctx)
+	go m.reconcilePeriodically(ctx, core.ReconcileInterval)
+
+	for ctx.Err() == nil {
+		batch, err := m.nextBatch(ctx)
 		if err != nil {
 			core.L.Error(fmt.Sprintf("Failed to read task queue, due to {%s}", err))
-			continue
-		}
-		fmt.Printf("result--->: %s\n", results[1])
-		task := core.Task{}
-		if err := json.Unmarshal([]byte(results[1]), &task); err != nil {
-			core.L.Error(fmt.Sprintf("Failed to parse task queue, due to {%s}", err))
-			continue
+			// back off instead of spinning while Redis is unavailable
+			time.Sleep(time.Second)
 		}
-		fmt.Printf("task: %+v\n", task)
-		pkTaskResult := fmt.Sprintf("%s%d", core.PkTaskResult, task.TaskId)
-		taskResultStr, err := json.Marshal(task.TaskResult)
-		if err != nil {
-			core.L.Error(fmt.Sprintf("Failed to marshal task result, due to {%s}", err))
-			return
+		if len(batch) > 0 {
+			m.deliverBatch(ctx, batch)
 		}
-		if err := core.S.RedisConn.LPush(ctx, pkTaskResult, taskResultStr).Err(); err != nil {
-			core.L.Error(fmt.Sprintf("Failed to save task result, due to {%s}", err))
-			return
-		}
-		m.verifyTask(ctx, task.TaskId)
 	}
 }
 
-// verifyTask is a method of the Monitor struct. It is responsible for verifying a task
-// by reading the task result from Redis and checking if the result has reached a
-// certain threshold. If the threshold is met, it sets the task as finished in Redis,
-// logs the task result and operators, and sends the task result to a specified
-// destination.
-//
-// The function takes a context.Context object and a uint64 taskId as parameters.
-// It does not return anything.
-func (m *Monitor) verifyTask(ctx context.Context, taskId uint64) {
-	pkTaskResult := fmt.Sprintf("%s%d", core.PkTaskResult, taskId)
-	// timer to read redis queue and verify the task result
-	results, err := core.S.RedisConn.LRange(ctx, pkTaskResult, 0, -1).Result()
-	fmt.Printf("verify results: %s\n", results)
+// failTask schedules a retry of a task that could not be delivered, or dead-letters it.
+func (m *Monitor) failTask(ctx context.Context, item string, task *core.Task, cause error) {
+	deadLettered, err := core.FailTask(ctx, item, task, cause, time.Now())
 	if err != nil {
-		core.L.Error(fmt.Sprintf("Failed to read task result, due to {%s}", err))
+		// the task stays in the processing list and is recovered on the next start
+		core.L.Error(fmt.Sprintf("Failed to reschedule task, due to {%s}", err))
 		return
 	}
+	if deadLettered {
+		core.L.Error(fmt.Sprintf("Task moved to the dead-letter list: {%s}", item))
+	}
+}
 
-	resultCntMap := make(map[int64]uint)
-	resultOperatorMap := make(map[int64][]string)
-	var taskResult core.TaskResult
-	for _, result := range results {
-		fmt.Printf("verify result: %s\n", result)
-		if err := json.Unmarshal([]byte(result), &taskResult); err != nil {
-			core.L.Error(fmt.Sprintf("Failed to parse task result, due to {%s}", err))
+// requeueRetries moves failed tasks back to the queue once their backoff passed, until ctx is done.
+func (m *Monitor) requeueRetries(ctx context.Context) {
+	ticker := time.NewTicker(core.RetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
 		}
-		resultCntMap[taskResult.Result]++
-		resultOperatorMap[taskResult.Result] = append(resultOperatorMap[taskResult.Result], taskResult.Operator)
-		if resultCntMap[taskResult.Result] >= core.C.App.Threshold {
-			pkTaskFinished := fmt.Sprintf("%s%d", core.PkTaskFinished, taskId)
-			if err := core.S.RedisConn.Set(ctx, pkTaskFinished, taskResult.Result, 0).Err(); err != nil {
-				core.L.Error(fmt.Sprintf("Failed to set task finished, due to {%s}", err))
-				return
-			}
-			operators := strings.Join(resultOperatorMap[taskResult.Result], "&")
-			core.L.Info(fmt.Sprintf("Task {%d} is finished. The result is {%d}. The operators are {%s}", taskId, taskResult.Result, operators))
-			if err := m.sendTaskResult(taskId, taskResult.Result, operators); err != nil {
-				core.L.Error(fmt.Sprintf("Failed to send task result, due to {%s}", err))
-			}
-			pkTaskOperator := fmt.Sprintf("%s%d", core.PkTaskOperator, taskId)
-			core.S.RedisConn.Del(ctx, pkTaskResult)
-			core.S.RedisConn.Del(ctx, pkTaskOperator)
-			break
+		if _, err := core.RequeueDueRetries(ctx, time.Now()); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to requeue task retries, due to {%s}", err))
 		}
 	}
 }
 
 // sendTaskResult sends the task result to BVS Squaring API.
 //
+// ctx: the context for the transaction
 // taskId: the unique identifier of the task
 // result: the result of the task
 // operators: the operators involved in the task
 // error: an error if the task result sending fails
-func (m *Monitor) sendTaskResult(taskId uint64, result int64, operators string) error {
+func (m *Monitor) sendTaskResult(ctx context.Context, taskId uint64, result int64, operators string) error {
 	fmt.Println("sendTaskResult", taskId, result, operators)
 
 	bvsSquaring := BvsSquaringApi.NewBVSSquaring(m.chainIO)
 	bvsSquaring.BindClient(m.bvsContract)
-	_, err := bvsSquaring.RespondToTask(context.Background(), int64(taskId), result, operators)
+	_, err := bvsSquaring.RespondToTask(ctx, int64(taskId), result, operators)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
-
-func (m *Monitor) VerifyOperator(operator string) (bool, error) {
-	rsp, err := m.bvsDirectoryApi.QueryOperator(operator, operator)
-	if err != nil {
-		core.L.Error(fmt.Sprintf("Failed to query operator, due to {%s}", err))
-		return false, err
-	}
-	fmt.Printf("txnRsp: %+v\n", rsp)
-	if rsp.Status == "registered" {
-		return true, nil
-	}
-
-	return false, nil
-}
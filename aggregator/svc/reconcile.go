@@ -0,0 +1,131 @@
+package svc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	BvsSquaringApi "github.com/satlayer/hello-world-bvs/bvs_squaring_api"
+)
+
+// isResultSubmitted reports whether err is the squaring contract refusing a second response.
+func isResultSubmitted(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "task result already submitted")
+}
+
+// TaskResult returns the result the squaring contract recorded for a task.
+//
+// taskId is the unique identifier of the task.
+// Returns the result and true, false if no result was submitted yet, or an error if the
+// contract could not be queried.
+func (m *Monitor) TaskResult(taskId uint64) (int64, bool, error) {
+	bvsSquaring := BvsSquaringApi.NewBVSSquaring(m.chainIO)
+	bvsSquaring.BindClient(m.bvsContract)
+	resp, err := bvsSquaring.GetTaskResult(int64(taskId))
+	if err != nil {
+		if strings.Contains(err.Error(), "no value found") {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to query task result: %v", err)
+	}
+	var result int64
+	if err := json.Unmarshal(resp.Data, &result); err != nil {
+		return 0, false, fmt.Errorf("failed to parse task result: %v", err)
+	}
+	return result, true, nil
+}
+
+// Reconcile compares the unconfirmed and pending tasks in Redis with the results on chain.
+//
+// Only tasks that wait for delivery, or were taken for delivery without their result being
+// confirmed, are checked, see core.TrackDeliveries. Pending deliveries of tasks whose result
+// already landed are dropped, and tasks whose result neither landed nor waits for delivery are
+// queued again. Tasks whose result landed, or that cannot be queued again, are confirmed, so
+// they are not checked again.
+// ctx is the context for the Redis calls.
+// Returns an error if Redis fails; errors of single tasks are logged and retried on the next pass.
+func (m *Monitor) Reconcile(ctx context.Context) error {
+	// unconfirmed tasks are read first, so a task acked in between is found on chain
+	unconfirmed, err := core.UnconfirmedTasks(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	pending, err := core.PendingDeliveries(ctx)
+	if err != nil {
+		return err
+	}
+
+	taskIds := make(map[uint64]bool, len(pending)+len(unconfirmed))
+	for taskId := range pending {
+		taskIds[taskId] = true
+	}
+	for _, taskId := range unconfirmed {
+		taskIds[taskId] = true
+	}
+
+	var dropped, requeued int
+	var settled []uint64
+	for taskId := range taskIds {
+		_, landed, err := m.TaskResult(taskId)
+		if err != nil {
+			core.L.Error(fmt.Sprintf("Failed to reconcile task {%d}, due to {%s}", taskId, err))
+			continue
+		}
+		if landed {
+			if len(pending[taskId]) > 0 {
+				n, err := core.DropDeliveries(ctx, pending[taskId])
+				if err != nil {
+					return err
+				}
+				dropped += int(n)
+			}
+			settled = append(settled, taskId)
+			continue
+		}
+		if len(pending[taskId]) > 0 {
+			continue
+		}
+		if _, err := core.RequeueFinishedTask(ctx, taskId); err != nil {
+			switch {
+			case err == core.ErrResultNotSubmittable:
+				// the outcome is only recorded in Redis
+				settled = append(settled, taskId)
+			case err == redis.Nil:
+				core.L.Error(fmt.Sprintf("Task {%d} is finished but never landed, and its verification data expired", taskId))
+				settled = append(settled, taskId)
+			default:
+				core.L.Error(fmt.Sprintf("Failed to requeue task {%d}, due to {%s}", taskId, err))
+			}
+			continue
+		}
+		requeued++
+	}
+	if err := core.ConfirmTasks(ctx, settled...); err != nil {
+		return err
+	}
+	if dropped > 0 || requeued > 0 {
+		core.L.Info(fmt.Sprintf("Reconciled {%d} tasks: dropped {%d} deliveries that already landed, requeued {%d} missing submissions", len(taskIds), dropped, requeued))
+	}
+	return nil
+}
+
+// reconcilePeriodically runs Reconcile every interval until ctx is done.
+func (m *Monitor) reconcilePeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.Reconcile(ctx); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to reconcile tasks, due to {%s}", err))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satlayer/satlayer-api/chainio/indexer"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// statusRegistered is the BVS directory status of an operator registered to the BVS.
+const statusRegistered = "registered"
+
+// VerifyOperator reports whether an operator is registered in the BVS directory.
+//
+// The status is cached in Redis, for core.RegistrationTTL if the operator is registered and
+// core.UnregisteredTTL otherwise. Registration events refresh the cache, see WatchRegistrations.
+// ctx is the context for the cache.
+// operator is the address of the operator.
+// Returns whether the operator is registered, or an error if the status could not be read.
+func (m *Monitor) VerifyOperator(ctx context.Context, operator string) (bool, error) {
+	status, err := core.S.RedisConn.Get(ctx, core.PkOperatorRegistration+operator).Result()
+	if err == nil {
+		METRICS.RegistrationCacheHits.Inc()
+		return status == statusRegistered, nil
+	}
+	if err != redis.Nil {
+		core.L.Error(fmt.Sprintf("Failed to read operator registration, due to {%s}", err))
+	}
+
+	METRICS.RegistrationCacheMisses.Inc()
+	return m.RefreshOperator(ctx, operator)
+}
+
+// RefreshOperator queries an operator's registration from the BVS directory and caches it.
+//
+// ctx is the context for the cache.
+// operator is the address of the operator.
+// Returns whether the operator is registered, or an error if the directory could not be queried.
+func (m *Monitor) RefreshOperator(ctx context.Context, operator string) (bool, error) {
+	rsp, err := m.bvsDirectoryApi.QueryOperator(operator, operator)
+	if err != nil {
+		core.L.Error(fmt.Sprintf("Failed to query operator, due to {%s}", err))
+		return false, fmt.Errorf("failed to query operator: %v", err)
+	}
+	cacheRegistration(ctx, operator, rsp.Status)
+	return rsp.Status == statusRegistered, nil
+}
+
+// RefreshOperators refreshes the cached registration of every active operator.
+//
+// The BVS directory cannot list the operators of a BVS, so the cache is warmed from the
+// operators in core.PkActiveOperators.
+// ctx is the context for the cache.
+// Returns the number of refreshed and of registered operators, or an error if the known
+// operators could not be read. Operators that fail to refresh are logged and skipped.
+func (m *Monitor) RefreshOperators(ctx context.Context) (int, int, error) {
+	operators, err := core.S.RedisConn.ZRange(ctx, core.PkActiveOperators, 0, -1).Result()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read active operators: %v", err)
+	}
+	refreshed, registered := 0, 0
+	for _, operator := range operators {
+		ok, err := m.RefreshOperator(ctx, operator)
+		if err != nil {
+			continue
+		}
+		refreshed++
+		if ok {
+			registered++
+		}
+	}
+	return refreshed, registered, nil
+}
+
+// WatchRegistrations keeps the registration cache up to date with the BVS directory's
+// registration events, until ctx is done.
+//
+// The indexer is restarted from the latest block if it stops.
+// ctx is the context for the indexer.
+// No return values.
+func (m *Monitor) WatchRegistrations(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := m.watchRegistrations(ctx); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to watch operator registrations, due to {%s}", err))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(core.RetryBaseDelay):
+		}
+	}
+}
+
+// watchRegistrations applies registration events from the latest block on until the indexer stops.
+func (m *Monitor) watchRegistrations(ctx context.Context) error {
+	latestBlock, err := m.LatestHeight(ctx)
+	if err != nil {
+		return err
+	}
+	evtIndexer := indexer.NewEventIndexer(
+		m.chainIO.GetClientCtx(),
+		core.C.Chain.BvsDirectory,
+		latestBlock,
+		[]string{core.EventOperatorRegistration},
+		1,
+		10)
+	evtChain, err := evtIndexer.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run registration indexer: %v", err)
+	}
+	for evt := range evtChain {
+		operator := evt.AttrMap["operator"]
+		if operator == "" {
+			continue
+		}
+		if bvs, ok := evt.AttrMap["bvs"]; ok && bvs != m.bvsContract {
+			continue
+		}
+		status, ok := evt.AttrMap["status"]
+		if !ok {
+			// unknown event format, let the next lookup query the directory
+			if err := core.S.RedisConn.Del(ctx, core.PkOperatorRegistration+operator).Err(); err != nil {
+				core.L.Error(fmt.Sprintf("Failed to drop operator registration, due to {%s}", err))
+			}
+			continue
+		}
+		core.L.Info(fmt.Sprintf("Operator {%s} registration changed to {%s} at height {%d}", operator, status, evt.BlockHeight))
+		cacheRegistration(ctx, operator, status)
+	}
+	return fmt.Errorf("registration indexer stopped")
+}
+
+// cacheRegistration caches an operator's registration status.
+//
+// An operator that is not registered is no longer expected to respond to tasks.
+func cacheRegistration(ctx context.Context, operator string, status string) {
+	ttl := core.UnregisteredTTL
+	if status == statusRegistered {
+		ttl = core.RegistrationTTL
+	} else if err := core.S.RedisConn.ZRem(ctx, core.PkActiveOperators, operator).Err(); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to drop active operator, due to {%s}", err))
+	}
+	if err := core.S.RedisConn.Set(ctx, core.PkOperatorRegistration+operator, status, ttl).Err(); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to cache operator registration, due to {%s}", err))
+	}
+}
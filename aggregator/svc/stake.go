@@ -0,0 +1,115 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satlayer/satlayer-api/chainio/types"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// StakeEpoch returns the epoch stake snapshots are taken in at time t.
+func StakeEpoch(t time.Time) int64 {
+	return t.Unix() / int64(core.StakeEpoch.Seconds())
+}
+
+// TaskStakeEpoch returns the epoch of the stake snapshot a task's attesters are weighted with.
+//
+// The epoch is fixed by the first attester of the task, so a task that is still collecting votes
+// when the epoch ends keeps weighting them with the same snapshot.
+// ctx is the context for Redis.
+// taskId is the task the attester votes on.
+// Returns the epoch, or an error if it could not be read.
+func (m *Monitor) TaskStakeEpoch(ctx context.Context, taskId uint64) (int64, error) {
+	key := fmt.Sprintf("%s%d", core.PkTaskStakeEpoch, taskId)
+	// the snapshot must outlive the task, which is finalized after its timeout at the latest
+	if err := core.S.RedisConn.SetNX(ctx, key, StakeEpoch(time.Now()), 24*time.Hour).Err(); err != nil {
+		return 0, fmt.Errorf("failed to set task stake epoch: %v", err)
+	}
+	epoch, err := core.S.RedisConn.Get(ctx, key).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read task stake epoch: %v", err)
+	}
+	return epoch, nil
+}
+
+// OperatorStake returns the stake delegated to an operator in the given epoch.
+//
+// The stake is the sum of the shares of all stakers over all strategies, read from the
+// delegation manager's GetOperatorStakers and converted to one unit by [stake] strategies.
+// The first lookup in an epoch is cached in Redis, so every vote weighted with that epoch's
+// snapshot, see TaskStakeEpoch, gets the same stake.
+// ctx is the context for the cache.
+// operator is the address of the operator.
+// epoch is the epoch of the snapshot, see StakeEpoch.
+// Returns the stake as a decimal string, or an error if it could not be read.
+func (m *Monitor) OperatorStake(ctx context.Context, operator string, epoch int64) (string, error) {
+	key := fmt.Sprintf("%s%d:%s", core.PkOperatorStake, epoch, operator)
+	stake, err := core.S.RedisConn.Get(ctx, key).Result()
+	if err == nil {
+		return stake, nil
+	}
+	if err != redis.Nil {
+		return "", fmt.Errorf("failed to read stake snapshot: %v", err)
+	}
+
+	rsp, err := m.delegation.GetOperatorStakers(operator)
+	if err != nil {
+		return "", fmt.Errorf("failed to get operator stakers: %v", err)
+	}
+	stake, err = totalStake(rsp, core.C.Stake.Strategies)
+	if err != nil {
+		return "", err
+	}
+
+	// keep the first snapshot of the epoch if another aggregator raced us
+	if err := core.S.RedisConn.SetNX(ctx, key, stake, 2*core.StakeEpoch).Err(); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to cache stake snapshot, due to {%s}", err))
+	}
+	if cached, err := core.S.RedisConn.Get(ctx, key).Result(); err == nil {
+		stake = cached
+	}
+	return stake, nil
+}
+
+// totalStake sums the shares of all stakers of an operator over all strategies.
+//
+// Each strategy's shares are scaled from its decimals to StakeDecimals and multiplied by its
+// weight; shares of strategies that are not listed are skipped. Without any strategies, the
+// shares are summed as they are.
+func totalStake(rsp *types.GetOperatorStakersResp, strategies []core.Strategy) (string, error) {
+	factors := make(map[string]*big.Int, len(strategies))
+	for _, strategy := range strategies {
+		factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(core.StakeDecimals-strategy.Decimals)), nil)
+		if strategy.Weight > 0 {
+			factor.Mul(factor, new(big.Int).SetUint64(strategy.Weight))
+		}
+		factors[strategy.Address] = factor
+	}
+
+	total := new(big.Int)
+	for _, staker := range rsp.StakersAndShares {
+		for _, strategy := range staker.SharesPerStrategy {
+			if len(strategy) != 2 {
+				return "", fmt.Errorf("invalid shares of staker %s: %v", staker.Staker, strategy)
+			}
+			shares, ok := new(big.Int).SetString(strategy[1], 10)
+			if !ok || shares.Sign() < 0 {
+				return "", fmt.Errorf("invalid shares of staker %s: %s", staker.Staker, strategy[1])
+			}
+			if len(factors) > 0 {
+				factor, ok := factors[strategy[0]]
+				if !ok {
+					continue
+				}
+				shares.Mul(shares, factor)
+			}
+			total.Add(total, shares)
+		}
+	}
+	return total.String(), nil
+}
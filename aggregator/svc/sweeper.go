@@ -0,0 +1,98 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// LatestHeight returns the chain's latest block height.
+//
+// ctx is the context for the node status query.
+// Returns the height, or an error if the chain cannot be queried.
+func (m *Monitor) LatestHeight(ctx context.Context) (int64, error) {
+	res, err := m.chainIO.QueryNodeStatus(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query node status: %v", err)
+	}
+	return res.SyncInfo.LatestBlockHeight, nil
+}
+
+// TaskDeadline returns the deadline of a task first seen now under policy.
+//
+// The chain is only queried if the policy sets a block height deadline.
+// ctx is the context for the node status query.
+// policy is the consensus policy for the task's type.
+// Returns the deadline, or an error if the latest block height cannot be read.
+func (m *Monitor) TaskDeadline(ctx context.Context, policy core.ConsensusPolicy) (core.Deadline, error) {
+	var height int64
+	if policy.TimeoutBlocks > 0 {
+		var err error
+		if height, err = m.LatestHeight(ctx); err != nil {
+			return core.Deadline{}, err
+		}
+	}
+	return core.NewDeadline(time.Now(), height, policy), nil
+}
+
+// SweepExpiredTasks finalizes tasks whose deadline passed without consensus, until ctx is done.
+//
+// Expired tasks are queued with the result code of their timeout outcome, so Run submits them
+// like any other task.
+// ctx is the context for the sweeper.
+// interval is how often expired tasks are looked for.
+func (m *Monitor) SweepExpiredTasks(ctx context.Context, interval time.Duration) {
+	core.L.Info("Start to sweep expired tasks")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := m.sweepExpiredTasks(ctx); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to sweep expired tasks, due to {%s}", err))
+		}
+	}
+}
+
+func (m *Monitor) sweepExpiredTasks(ctx context.Context) error {
+	// the chain is only queried while some task has a block height deadline
+	var height int64
+	hasHeights, err := core.HasDeadlineHeights(ctx)
+	if err != nil {
+		return err
+	}
+	if hasHeights {
+		if height, err = m.LatestHeight(ctx); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to get latest height, only checking wall clock deadlines, due to {%s}", err))
+		}
+	}
+
+	now := time.Now()
+	taskIds, err := core.ExpiredTasks(ctx, now, height)
+	if err != nil {
+		return err
+	}
+	for _, taskId := range taskIds {
+		performer, err := m.AssignedPerformer(ctx, taskId)
+		if err != nil && !errors.Is(err, ErrUnknownTask) {
+			// retried on the next sweep
+			core.L.Error(fmt.Sprintf("Failed to get assigned performer of expired task {%d}, due to {%s}", taskId, err))
+			continue
+		}
+		timeout, err := core.ExpireTask(ctx, taskId, performer, now)
+		if err != nil {
+			core.L.Error(fmt.Sprintf("Failed to expire task {%d}, due to {%s}", taskId, err))
+			continue
+		}
+		if timeout != nil {
+			core.L.Info(fmt.Sprintf("Task {%d} expired: {%s}, result {%d}. Operators that never responded: {%v}", taskId, timeout.Outcome, timeout.Result, timeout.Missing))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package svc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/satlayer/satlayer-api/chainio/api"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// ErrTaskTypeNotIndexed is returned when a task has no network in the StateBank cache yet, and
+// the StateBank events are not indexed up to the latest block to tell it never had one.
+var ErrTaskTypeNotIndexed = errors.New("task network not indexed yet")
+
+// taskTypeTTL is how long a task's type is cached, as long as its verification data is kept.
+const taskTypeTTL = 24 * time.Hour
+
+// TaskType returns the type of a task, i.e. the network it targets.
+//
+// The type is the StateBank key "taskNetwork.<id>" the operators read, as cached by
+// WatchStateBank, or core.DefaultTaskType if the key was never set. A task without the key is
+// only given the default type once the StateBank events are indexed up to the latest block,
+// and the type is then cached so it cannot change while the task collects submissions.
+// ctx is the context for the cache and the node status query.
+// taskId is the unique identifier of the task.
+// Returns the task type, ErrTaskTypeNotIndexed if the key may not be indexed yet, or an error if
+// the type could not be read.
+func (m *Monitor) TaskType(ctx context.Context, taskId uint64) (string, error) {
+	key := fmt.Sprintf("%s%d", core.PkTaskNetwork, taskId)
+	taskType, err := core.S.RedisConn.Get(ctx, key).Result()
+	if err == nil {
+		return taskType, nil
+	}
+	if err != redis.Nil {
+		return "", fmt.Errorf("failed to read task network: %v", err)
+	}
+
+	synced, err := core.S.RedisConn.Get(ctx, core.PkStateBankSynced).Int64()
+	if err != nil && err != redis.Nil {
+		return "", fmt.Errorf("failed to read state bank height: %v", err)
+	}
+	latest, err := m.LatestHeight(ctx)
+	if err != nil {
+		return "", err
+	}
+	if synced < latest {
+		return "", ErrTaskTypeNotIndexed
+	}
+
+	// the indexer may have stored the key in the meantime
+	if err := core.S.RedisConn.SetNX(ctx, key, core.DefaultTaskType, taskTypeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to cache task network: %v", err)
+	}
+	taskType, err = core.S.RedisConn.Get(ctx, key).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read task network: %v", err)
+	}
+	return taskType, nil
+}
+
+// WatchStateBank caches the task networks set in the StateBank, see TaskType, until ctx is done.
+//
+// The indexer resumes from the last indexed block, or starts at [chain] stateBankStart on the
+// first run, and is restarted if it stops.
+// ctx is the context for the indexer.
+// No return values.
+func (m *Monitor) WatchStateBank(ctx context.Context) {
+	for ctx.Err() == nil {
+		if err := m.watchStateBank(ctx); err != nil {
+			core.L.Error(fmt.Sprintf("Failed to watch the state bank, due to {%s}", err))
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(core.RetryBaseDelay):
+		}
+	}
+}
+
+// watchStateBank caches StateBank updates until the indexer stops.
+//
+// Whenever the indexer is up to date and every event was handled, the latest block height is
+// recorded as indexed, which lets TaskType tell a task without a network key from one whose key
+// was not indexed yet.
+func (m *Monitor) watchStateBank(ctx context.Context) error {
+	startHeight, err := m.stateBankStart(ctx)
+	if err != nil {
+		return err
+	}
+	stateBank := api.NewStateBankImpl(m.chainIO)
+	idx := stateBank.Indexer(m.chainIO.GetClientCtx(), core.C.Chain.StateBank, m.bvsContract, startHeight, []string{core.EventStateBankUpdate}, 1, 10)
+	evtChain, err := idx.Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to run state bank indexer: %v", err)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case evt, ok := <-evtChain:
+			if !ok {
+				return fmt.Errorf("state bank indexer stopped")
+			}
+			cacheTaskNetwork(ctx, evt.AttrMap["key"], evt.AttrMap["value"])
+		case <-ticker.C:
+			// the head is read first, so an up to date indexer has handled every block up to it
+			latest, err := m.LatestHeight(ctx)
+			if err != nil {
+				core.L.Error(fmt.Sprintf("Failed to read latest height, due to {%s}", err))
+				continue
+			}
+			if !idx.IsUpToDate || len(evtChain) > 0 {
+				continue
+			}
+			if err := core.S.RedisConn.Set(ctx, core.PkStateBankSynced, latest, 0).Err(); err != nil {
+				core.L.Error(fmt.Sprintf("Failed to record state bank height, due to {%s}", err))
+			}
+		}
+	}
+}
+
+// stateBankStart returns the height the state bank indexer starts at.
+func (m *Monitor) stateBankStart(ctx context.Context) (int64, error) {
+	synced, err := core.S.RedisConn.Get(ctx, core.PkStateBankSynced).Int64()
+	if err == nil {
+		return synced + 1, nil
+	}
+	if err != redis.Nil {
+		return 0, fmt.Errorf("failed to read state bank height: %v", err)
+	}
+	if core.C.Chain.StateBankStart > 0 {
+		return core.C.Chain.StateBankStart, nil
+	}
+	return m.LatestHeight(ctx)
+}
+
+// cacheTaskNetwork caches a StateBank update if it sets a task's network.
+func cacheTaskNetwork(ctx context.Context, key string, value string) {
+	id, ok := strings.CutPrefix(key, core.StateBankTaskNetwork)
+	if !ok {
+		return
+	}
+	taskId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return
+	}
+	taskType := value
+	if taskType == "" {
+		taskType = core.DefaultTaskType
+	}
+	if err := core.S.RedisConn.Set(ctx, fmt.Sprintf("%s%d", core.PkTaskNetwork, taskId), taskType, taskTypeTTL).Err(); err != nil {
+		core.L.Error(fmt.Sprintf("Failed to cache task network, due to {%s}", err))
+	}
+}
@@ -0,0 +1,113 @@
+package svc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/prober"
+)
+
+// VERIFIER checks performer results against the trusted RPCs of [verifier].
+var VERIFIER *Verifier
+
+func init() {
+	verifier, err := NewVerifier(core.C.Verifier.Networks)
+	if err != nil {
+		panic(err)
+	}
+	VERIFIER = verifier
+}
+
+// Verifier checks performer blocks the same way attesters do, with a quorum of trusted RPCs
+// per task type.
+type Verifier struct {
+	networks map[string]*verifierNetwork
+}
+
+type verifierNetwork struct {
+	prober *prober.QuorumProber
+	maxLag int64
+}
+
+// NewVerifier builds a quorum prober for every network.
+//
+// networks are the trusted RPCs by task type.
+// Returns the verifier, or an error if a network is misconfigured.
+func NewVerifier(networks []core.Network) (*Verifier, error) {
+	v := &Verifier{networks: make(map[string]*verifierNetwork, len(networks))}
+	for _, nw := range networks {
+		name := nw.Name
+		if name == "" {
+			name = core.DefaultTaskType
+		}
+		if _, exists := v.networks[name]; exists {
+			return nil, fmt.Errorf("duplicate verifier network: %s", name)
+		}
+		upstreams := make([]prober.Upstream, 0, len(nw.Endpoints))
+		for _, endpoint := range nw.Endpoints {
+			p, err := prober.NewProber(nw.Kind, endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("verifier network %s: %v", name, err)
+			}
+			upstreams = append(upstreams, prober.Upstream{Name: endpoint, Prober: p})
+		}
+		qp, err := prober.NewQuorumProber(upstreams, nw.Quorum)
+		if err != nil {
+			return nil, fmt.Errorf("verifier network %s: %v", name, err)
+		}
+		qp.OnDisagreement = func(upstream string, height int64, reason string) {
+			core.L.Error(fmt.Sprintf("Trusted RPC {%s} of network {%s} disagreed at height {%d}: %s", upstream, name, height, reason))
+		}
+		maxLag := nw.MaxLag
+		if maxLag <= 0 {
+			maxLag = prober.DefaultMaxLag
+		}
+		v.networks[name] = &verifierNetwork{prober: qp, maxLag: maxLag}
+	}
+	return v, nil
+}
+
+// Check verifies a performer's block against the trusted RPCs of its task type.
+//
+// The block is valid if the trusted RPCs agree on its hash at its height and it is no more
+// than maxLag blocks behind their head, as attesters check it.
+// ctx is the context for the RPC calls, bounded by core.CheckTimeout.
+// taskType is the network the task targets.
+// result is the performer's "blockNumber-blockHash".
+// Returns the verdict, core.CheckError if the task type has no trusted RPCs or they could not be read.
+func (v *Verifier) Check(ctx context.Context, taskType string, result string) *core.PerformerCheck {
+	check := &core.PerformerCheck{CheckedAt: time.Now().Unix()}
+	defer func() {
+		METRICS.PerformerChecks.WithLabelValues(check.Verdict).Inc()
+	}()
+
+	nw, ok := v.networks[taskType]
+	if !ok {
+		check.Verdict = core.CheckError
+		check.Error = fmt.Sprintf("no trusted RPCs configured for task type %s", taskType)
+		return check
+	}
+	claimed, err := prober.ParseResult(result)
+	if err != nil {
+		check.Verdict = core.CheckInvalid
+		check.Error = err.Error()
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, core.CheckTimeout)
+	defer cancel()
+	valid, err := prober.Verify(ctx, nw.prober, claimed, nw.maxLag)
+	switch {
+	case err != nil:
+		check.Verdict = core.CheckError
+		check.Error = err.Error()
+	case valid:
+		check.Verdict = core.CheckValid
+	default:
+		check.Verdict = core.CheckInvalid
+		check.Error = fmt.Sprintf("block %d is not %s on the trusted RPCs or more than %d blocks behind", claimed.Height, claimed.Hash, nw.maxLag)
+	}
+	return check
+}
@@ -0,0 +1,201 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/rand"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/api"
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// TestAdminResolveTask resolves a task by hand, then purges it.
+//
+// It needs the Redis configured in env.toml.
+func TestAdminResolveTask(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(9_000_000 + rand.Intn(1_000_000))
+	defer core.PurgeTask(ctx, taskId)
+
+	if _, err := core.ResolveTask(ctx, taskId, core.ResultRejected, "", "test", "no performer"); !errors.Is(err, core.ErrNoOperator) {
+		t.Fatalf("expected ErrNoOperator, got %v", err)
+	}
+	operator, err := core.ResolveTask(ctx, taskId, core.ResultRejected, "admin-operator", "test", "stuck task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if operator != "admin-operator" {
+		t.Fatalf("expected the given operator, got %s", operator)
+	}
+	if _, err := core.ResolveTask(ctx, taskId, core.ResultApproved, "admin-operator", "test", "again"); !errors.Is(err, core.ErrTaskFinished) {
+		t.Fatalf("expected ErrTaskFinished, got %v", err)
+	}
+
+	state, err := core.LoadTaskState(ctx, taskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verification core.TaskVerification
+	if err := json.Unmarshal(state.Verification, &verification); err != nil {
+		t.Fatal(err)
+	}
+	resolution := verification.Resolution
+	if verification.Status != core.VoteResolved || resolution == nil {
+		t.Fatalf("expected the task to be resolved, got %+v", verification)
+	}
+	if resolution.Result != core.ResultRejected || resolution.Operator != "admin-operator" || resolution.Actor != "test" || resolution.Reason != "stuck task" {
+		t.Fatalf("unexpected resolution %+v", resolution)
+	}
+	if !state.Finished {
+		t.Fatal("expected the task to be finished")
+	}
+	queued := state.Deliveries[core.PkTaskQueue]
+	if len(queued) != 1 {
+		t.Fatalf("expected the task to be queued once, got %v", queued)
+	}
+	var task core.Task
+	if err := json.Unmarshal([]byte(queued[0]), &task); err != nil {
+		t.Fatal(err)
+	}
+	if task.TaskResult.Operator != "admin-operator" || task.TaskResult.Result != core.ResultRejected {
+		t.Fatalf("unexpected queued task %+v", task)
+	}
+
+	if _, err := core.PurgeTask(ctx, taskId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := core.LoadTaskState(ctx, taskId); !errors.Is(err, core.ErrTaskNotFound) {
+		t.Fatalf("expected the task to be purged, got %v", err)
+	}
+}
+
+// TestAdminBanOperator bans and unbans an operator.
+//
+// It needs the Redis configured in env.toml.
+func TestAdminBanOperator(t *testing.T) {
+	ctx := context.Background()
+	address := fmt.Sprintf("banned-operator-%d", time.Now().UnixNano())
+	defer core.UnbanOperator(ctx, address)
+
+	if changed, err := core.BanOperator(ctx, address); err != nil || !changed {
+		t.Fatalf("expected the operator to be banned, got %v, %v", changed, err)
+	}
+	if changed, err := core.BanOperator(ctx, address); err != nil || changed {
+		t.Fatalf("expected the operator to be banned already, got %v, %v", changed, err)
+	}
+	if banned, err := core.IsBanned(ctx, address); err != nil || !banned {
+		t.Fatalf("expected the operator to be banned, got %v, %v", banned, err)
+	}
+	if changed, err := core.UnbanOperator(ctx, address); err != nil || !changed {
+		t.Fatalf("expected the operator to be unbanned, got %v, %v", changed, err)
+	}
+	if banned, err := core.IsBanned(ctx, address); err != nil || banned {
+		t.Fatalf("expected the operator not to be banned, got %v, %v", banned, err)
+	}
+}
+
+// TestAdminRoutesRequireToken checks that the routes outside the admin group that expose or
+// change state reject requests without the admin token.
+//
+// It needs the Redis configured in env.toml, where the audit log is written.
+func TestAdminRoutesRequireToken(t *testing.T) {
+	router := gin.New()
+	api.SetupRoutes(router)
+	token := core.C.Admin.Token
+	defer func() { core.C.Admin.Token = token }()
+
+	routes := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/api/aggregator/dead-letters"},
+		{http.MethodPost, "/api/aggregator/dead-letters/redrive"},
+		{http.MethodPost, "/api/aggregator/dead-letters/1/redrive"},
+		{http.MethodPost, "/api/aggregator/operators/registrations/refresh"},
+		{http.MethodPost, "/api/aggregator/operators/test-operator/registration/refresh"},
+	}
+	for _, route := range routes {
+		core.C.Admin.Token = ""
+		req, _ := http.NewRequest(route.method, route.path, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected %d while the admin API is disabled, got %d", route.method, route.path, http.StatusForbidden, w.Code)
+		}
+
+		core.C.Admin.Token = "test-token"
+		req, _ = http.NewRequest(route.method, route.path, nil)
+		req.Header.Set("Authorization", "Bearer wrong-token")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s %s: expected %d with a wrong token, got %d", route.method, route.path, http.StatusUnauthorized, w.Code)
+		}
+	}
+}
+
+// TestAdminAuditRejected checks that requests without a valid token are kept out of the audit
+// log and written to the log of rejected requests, and that authenticated requests still are.
+//
+// It needs the Redis configured in env.toml.
+func TestAdminAuditRejected(t *testing.T) {
+	ctx := context.Background()
+	router := gin.New()
+	api.SetupRoutes(router)
+	token := core.C.Admin.Token
+	defer func() { core.C.Admin.Token = token }()
+	core.C.Admin.Token = "test-token"
+
+	rand.Seed(uint64(time.Now().UnixNano()))
+	path := fmt.Sprintf("/api/aggregator/admin/tasks/%d", 9_000_000+rand.Intn(1_000_000))
+	send := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Admin-Actor", "audit-test")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	logged := func(rejected bool) *core.AuditEntry {
+		entries, _, err := core.AuditLog(ctx, rejected, 0, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range entries {
+			if entries[i].Path == path {
+				return &entries[i]
+			}
+		}
+		return nil
+	}
+
+	if code := send("wrong-token"); code != http.StatusUnauthorized {
+		t.Fatalf("expected %d with a wrong token, got %d", http.StatusUnauthorized, code)
+	}
+	entry := logged(true)
+	if entry == nil {
+		t.Fatal("expected the rejected request in the log of rejected requests")
+	}
+	if entry.Status != http.StatusUnauthorized || entry.ReportedActor != "audit-test" {
+		t.Fatalf("unexpected rejected entry %+v", entry)
+	}
+	if logged(false) != nil {
+		t.Fatal("expected the rejected request to be kept out of the audit log")
+	}
+
+	if code := send("test-token"); code != http.StatusNotFound {
+		t.Fatalf("expected %d for an unknown task, got %d", http.StatusNotFound, code)
+	}
+	if entry := logged(false); entry == nil || entry.Status != http.StatusNotFound {
+		t.Fatalf("expected the authenticated request in the audit log, got %+v", entry)
+	}
+}
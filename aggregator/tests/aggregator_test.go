@@ -19,19 +19,12 @@ import (
 
 	"github.com/satlayer/hello-world-bvs/aggregator/api"
 	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/payload"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/exp/rand"
 )
 
-type Payload struct {
-	TaskId    uint64 `json:"taskID" binding:"required"`
-	Result    int64  `json:"result" binding:"required"`
-	Timestamp int64  `json:"timestamp" binding:"required"`
-	Signature string `json:"signature" binding:"required"`
-	PubKey    string `json:"pubKey" binding:"required"`
-}
-
 // TestAggregator tests the functionality of the aggregator.
 //
 // t is the testing object provided by Go's testing package.
@@ -73,32 +66,28 @@ func TestAggregator(t *testing.T) {
 	api.SetupRoutes(router)
 	rand.Seed(uint64(time.Now().UnixNano()))
 	i := rand.Intn(100000)
-	nowTs := time.Now().Unix()
-	msgPayload := fmt.Sprintf("%s-%d-%d-%d", core.C.Chain.BvsHash, nowTs, i, i)
-	t.Logf("msgPayload: %s\n", msgPayload)
-	signature, err := cs.GetSigner().Sign([]byte(msgPayload))
-	t.Logf("signature: %+v\n", signature)
-	payload := Payload{
+	submission := payload.Submission{
 		TaskId:    uint64(i),
-		Result:    int64(i),
-		Timestamp: nowTs,
-		Signature: signature,
+		Result:    "true",
+		Timestamp: time.Now().Unix(),
+		Nonce:     uint64(time.Now().UnixNano()),
 		PubKey:    pubKeyStr,
+		Role:      payload.RoleAttester,
 	}
-	t.Logf("payload: %+v\n", payload)
-	if err != nil {
+	if err := submission.Sign(cs.GetSigner(), core.C.Chain.Id, core.C.Chain.BvsHash); err != nil {
 		t.Fatalf("failed to sign: %v\n", err)
 		return
 	}
-	sendTaskResult(payload, router, t)
+	t.Logf("payload: %+v\n", submission)
+	sendTaskResult(submission, router, t)
 }
 
 // sendTaskResult sends a task result to the aggregator API.
 //
-// payload is the task result payload to be sent.
+// submission is the signed task result to be sent.
 // t is the testing object provided by Go's testing package.
-func sendTaskResult(payload Payload, router *gin.Engine, t *testing.T) {
-	jsonData, err := json.Marshal(payload)
+func sendTaskResult(submission payload.Submission, router *gin.Engine, t *testing.T) {
+	jsonData, err := json.Marshal(submission)
 	if err != nil {
 		fmt.Printf("Error marshaling JSON: %s", err)
 		return
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+func TestRetryDelay(t *testing.T) {
+	if d := core.RetryDelay(1); d != core.RetryBaseDelay {
+		t.Fatalf("expected the base delay after the first attempt, got %s", d)
+	}
+	if d := core.RetryDelay(3); d != 4*core.RetryBaseDelay {
+		t.Fatalf("expected the delay to double per attempt, got %s", d)
+	}
+	if d := core.RetryDelay(100); d != core.RetryMaxDelay {
+		t.Fatalf("expected the delay to be capped, got %s", d)
+	}
+}
+
+// TestDeliveryDeadLetter fails a task until it is dead-lettered and redrives it.
+//
+// It needs the Redis configured in env.toml.
+func TestDeliveryDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	task := core.Task{TaskId: uint64(5_000_000 + rand.Intn(1_000_000)), TaskResult: core.TaskResult{Operator: "performer", Result: 1}}
+	defer cleanupDelivery(ctx, task.TaskId)
+
+	b, _ := json.Marshal(task)
+	item := string(b)
+	cause := errors.New("rpc unavailable")
+	for attempt := 1; attempt <= core.MaxDeliveryAttempts; attempt++ {
+		// stand in for NextTask, which takes whatever is at the head of the shared queue
+		if err := core.S.RedisConn.LPush(ctx, core.PkTaskProcessing, item).Err(); err != nil {
+			t.Fatal(err)
+		}
+		deadLettered, err := core.FailTask(ctx, item, &task, cause, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deadLettered != (attempt == core.MaxDeliveryAttempts) {
+			t.Fatalf("attempt %d: unexpected dead letter %v", attempt, deadLettered)
+		}
+		processing, err := core.S.RedisConn.LRange(ctx, core.PkTaskProcessing, 0, -1).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if containsString(processing, item) {
+			t.Fatalf("attempt %d: task still processing", attempt)
+		}
+		if deadLettered {
+			break
+		}
+
+		// the retry is only requeued once its backoff passed
+		if n, err := core.RequeueDueRetries(ctx, time.Now()); err != nil || n != 0 {
+			t.Fatalf("attempt %d: requeued %d before the backoff, %v", attempt, n, err)
+		}
+		if _, err := core.RequeueDueRetries(ctx, time.Now().Add(core.RetryDelay(attempt)+time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		item = takeQueued(t, ctx, task.TaskId)
+		if err := json.Unmarshal([]byte(item), &task); err != nil {
+			t.Fatal(err)
+		}
+		if task.Attempts != attempt || task.LastError != cause.Error() {
+			t.Fatalf("attempt %d: unexpected task %+v", attempt, task)
+		}
+	}
+
+	redriven, err := core.RedriveDeadLetters(ctx, &task.TaskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(redriven) != 1 || redriven[0] != task.TaskId {
+		t.Fatalf("expected the task to be redriven, got %v", redriven)
+	}
+	if err := json.Unmarshal([]byte(takeQueued(t, ctx, task.TaskId)), &task); err != nil {
+		t.Fatal(err)
+	}
+	if task.Attempts != 0 {
+		t.Fatalf("expected a fresh attempt count, got %d", task.Attempts)
+	}
+
+	// redriving again finds nothing
+	if redriven, err := core.RedriveDeadLetters(ctx, &task.TaskId); err != nil || len(redriven) != 0 {
+		t.Fatalf("expected nothing to redrive, got %v, %v", redriven, err)
+	}
+}
+
+// TestReconcileHelpers checks the Redis side of reconciliation: finding pending deliveries,
+// dropping those that landed, queueing a lost one again and tracking unconfirmed tasks.
+//
+// It needs the Redis configured in env.toml.
+func TestReconcileHelpers(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(6_000_000 + rand.Intn(1_000_000))
+	defer cleanupTask(ctx, taskId)
+
+	policy := core.ConsensusPolicy{MinimumAttesters: 1, Threshold: 66, MinimumStake: "0", Quorum: core.QuorumByCount, Tie: core.TiePending}
+	for _, submission := range []core.TaskSubmission{
+		{Address: "reconcile-performer", Result: "100-ABCD", Role: core.RolePerformer},
+		{Address: "reconcile-attester", Result: "false", Role: core.RoleAttester},
+	} {
+		submission := submission
+		if _, err := core.RecordVote(ctx, taskId, &submission, policy, testDeadline()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := core.PendingDeliveries(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending[taskId]) != 1 {
+		t.Fatalf("expected one pending delivery, got %v", pending[taskId])
+	}
+
+	// the result landed: the delivery is dropped
+	if n, err := core.DropDeliveries(ctx, pending[taskId]); err != nil || n != 1 {
+		t.Fatalf("expected one delivery dropped, got %d, %v", n, err)
+	}
+
+	// the result was lost: the task is queued again from its verification data
+	task, err := core.RequeueFinishedTask(ctx, taskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.TaskResult.Operator != "reconcile-performer" || task.TaskResult.Result != core.ResultRejected {
+		t.Fatalf("unexpected requeued task %+v", task)
+	}
+	takeQueued(t, ctx, taskId)
+
+	// a task taken for delivery is unconfirmed until its result landed
+	now := time.Now()
+	if err := core.TrackDeliveries(ctx, []uint64{taskId}, now); err != nil {
+		t.Fatal(err)
+	}
+	unconfirmed, err := core.UnconfirmedTasks(ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsTask(unconfirmed, taskId) {
+		t.Fatalf("task %d not reported as unconfirmed", taskId)
+	}
+	if err := core.ConfirmTasks(ctx, taskId); err != nil {
+		t.Fatal(err)
+	}
+	if unconfirmed, err = core.UnconfirmedTasks(ctx, now); err != nil || containsTask(unconfirmed, taskId) {
+		t.Fatalf("expected task %d to be confirmed, got %v, %v", taskId, unconfirmed, err)
+	}
+
+	// tasks taken longer ago than their verification data is kept are dropped
+	if err := core.TrackDeliveries(ctx, []uint64{taskId}, now.Add(-25*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if unconfirmed, err = core.UnconfirmedTasks(ctx, now); err != nil || containsTask(unconfirmed, taskId) {
+		t.Fatalf("expected task %d to be dropped, got %v, %v", taskId, unconfirmed, err)
+	}
+}
+
+// takeQueued removes the given task from the queue and returns it.
+func takeQueued(t *testing.T, ctx context.Context, taskId uint64) string {
+	queued, err := core.S.RedisConn.LRange(ctx, core.PkTaskQueue, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range queued {
+		var task core.Task
+		if json.Unmarshal([]byte(item), &task) == nil && task.TaskId == taskId {
+			core.S.RedisConn.LRem(ctx, core.PkTaskQueue, 1, item)
+			return item
+		}
+	}
+	t.Fatalf("task %d not queued", taskId)
+	return ""
+}
+
+func cleanupDelivery(ctx context.Context, taskId uint64) {
+	for _, key := range []string{core.PkTaskQueue, core.PkTaskProcessing, core.PkTaskDeadLetter} {
+		items, _ := core.S.RedisConn.LRange(ctx, key, 0, -1).Result()
+		for _, item := range items {
+			var task core.Task
+			if json.Unmarshal([]byte(item), &task) == nil && task.TaskId == taskId {
+				core.S.RedisConn.LRem(ctx, key, 0, item)
+			}
+		}
+	}
+	items, _ := core.S.RedisConn.ZRange(ctx, core.PkTaskRetry, 0, -1).Result()
+	for _, item := range items {
+		var task core.Task
+		if json.Unmarshal([]byte(item), &task) == nil && task.TaskId == taskId {
+			core.S.RedisConn.ZRem(ctx, core.PkTaskRetry, item)
+		}
+	}
+}
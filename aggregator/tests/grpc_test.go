@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/api"
+	"github.com/satlayer/hello-world-bvs/aggregatorpb"
+)
+
+// TestGRPC tests the status codes of the gRPC API against the configured Redis.
+//
+// t is the testing object provided by Go's testing package.
+func TestGRPC(t *testing.T) {
+	client := newGRPCClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := client.SubmitResult(ctx, &aggregatorpb.SubmitResultRequest{
+		Version:   1,
+		TaskId:    1,
+		Result:    "true",
+		Timestamp: time.Now().Unix(),
+		Role:      "observer",
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an invalid role, got %v", err)
+	}
+
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(8_000_000 + rand.Intn(1_000_000))
+	_, err = client.GetPerformerData(ctx, &aggregatorpb.GetPerformerDataRequest{TaskId: taskId})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound for task %d without submissions, got %v", taskId, err)
+	}
+}
+
+// newGRPCClient serves the gRPC API over an in-memory listener for the duration of the test.
+//
+// t is the testing object provided by Go's testing package.
+// Returns a client connected to the server.
+func newGRPCClient(t *testing.T) aggregatorpb.AggregatorClient {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(api.GRPCOptions()...)
+	api.RegisterGRPC(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return aggregatorpb.NewAggregatorClient(conn)
+}
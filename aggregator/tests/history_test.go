@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// TestTaskHistory checks that tasks are listed by status and that finished tasks count
+// towards their operators' history.
+//
+// It needs the Redis configured in env.toml.
+func TestTaskHistory(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(7_000_000 + rand.Intn(1_000_000))
+	defer cleanupTask(ctx, taskId)
+
+	policy := core.ConsensusPolicy{MinimumAttesters: 2, Threshold: 66, MinimumStake: "0", Quorum: core.QuorumByCount, Tie: core.TiePending}
+	submissions := []core.TaskSubmission{
+		{Address: "history-performer", Result: "100-ABCD", Role: core.RolePerformer, Timestamp: 1},
+		{Address: "history-yes1", Result: "true", Role: core.RoleAttester, Timestamp: 2},
+		{Address: "history-yes2", Result: "true", Role: core.RoleAttester, Timestamp: 3},
+	}
+	for i, submission := range submissions {
+		submission := submission
+		if _, err := core.RecordVote(ctx, taskId, &submission, policy, testDeadline()); err != nil {
+			t.Fatal(err)
+		}
+		// the task moves between the status indexes as votes come in
+		if i == 1 {
+			tasks, _, err := core.ListTasks(ctx, core.VoteRecorded, 0, 100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !containsRecord(tasks, taskId) {
+				t.Fatalf("task %d not listed as recorded", taskId)
+			}
+		}
+	}
+
+	tasks, total, err := core.ListTasks(ctx, core.VoteApproved, 0, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total < 1 || !containsRecord(tasks, taskId) {
+		t.Fatalf("task %d not listed as approved", taskId)
+	}
+	if tasks, _, _ := core.ListTasks(ctx, core.VoteRecorded, 0, 100); containsRecord(tasks, taskId) {
+		t.Fatalf("task %d still listed as recorded", taskId)
+	}
+
+	task, err := core.LoadTask(ctx, taskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Consensus == nil || task.Consensus.Result != core.ResultApproved || len(task.Attesters) != 2 || task.Attesters["history-yes2"].Timestamp != 3 {
+		t.Fatalf("unexpected task history %+v", task)
+	}
+	// the history outlives the 24 hour verification data
+	if ttl, _ := core.S.RedisConn.TTL(ctx, core.PkTaskHistory+fmt.Sprint(taskId)).Result(); ttl >= 0 {
+		t.Fatalf("expected the history to be persisted, got ttl %s", ttl)
+	}
+
+	stats, operatorTasks, _, err := core.OperatorHistory(ctx, "history-yes1", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Attested != 1 || stats.Decided != 1 || stats.Agreed != 1 || stats.AgreementRate != 1 {
+		t.Fatalf("unexpected attester stats %+v", stats)
+	}
+	if len(operatorTasks) != 1 || operatorTasks[0].Role != core.RoleAttester || operatorTasks[0].Agreed == nil || !*operatorTasks[0].Agreed {
+		t.Fatalf("unexpected attester tasks %+v", operatorTasks)
+	}
+
+	stats, _, _, err = core.OperatorHistory(ctx, "history-performer", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Performed != 1 || stats.Approved != 1 {
+		t.Fatalf("unexpected performer stats %+v", stats)
+	}
+}
+
+func containsRecord(records []core.TaskRecord, taskId uint64) bool {
+	for _, record := range records {
+		if record.TaskId == taskId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// TestLeaderLease checks that only one replica holds the lease at a time, and that a
+// replica taking over gets a greater fencing token.
+//
+// It needs the Redis configured in env.toml, without a running aggregator.
+func TestLeaderLease(t *testing.T) {
+	ctx := context.Background()
+	if leader, err := core.CurrentLeader(ctx); err != redis.Nil {
+		t.Skipf("the lease is held by %+v (%v), stop the aggregator to run this test", leader, err)
+	}
+	defer core.ReleaseLease(ctx, "lease-a")
+	defer core.ReleaseLease(ctx, "lease-b")
+
+	tokenA, err := core.AcquireLease(ctx, "lease-a", time.Minute)
+	if err != nil || tokenA == 0 {
+		t.Fatalf("expected lease-a to acquire the lease, got %d, %v", tokenA, err)
+	}
+	if token, err := core.AcquireLease(ctx, "lease-b", time.Minute); err != nil || token != 0 {
+		t.Fatalf("expected lease-b not to acquire a held lease, got %d, %v", token, err)
+	}
+	// renewing keeps the token
+	if token, err := core.AcquireLease(ctx, "lease-a", time.Minute); err != nil || token != tokenA {
+		t.Fatalf("expected lease-a to renew with token %d, got %d, %v", tokenA, token, err)
+	}
+	if err := core.CheckFence(ctx, tokenA); err != nil {
+		t.Fatalf("expected token %d to be current, got %v", tokenA, err)
+	}
+
+	// only the holder can release the lease
+	if err := core.ReleaseLease(ctx, "lease-b"); err != nil {
+		t.Fatal(err)
+	}
+	if leader, err := core.CurrentLeader(ctx); err != nil || leader.Holder != "lease-a" {
+		t.Fatalf("expected lease-a to still hold the lease, got %+v, %v", leader, err)
+	}
+	if err := core.ReleaseLease(ctx, "lease-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenB, err := core.AcquireLease(ctx, "lease-b", time.Minute)
+	if err != nil || tokenB <= tokenA {
+		t.Fatalf("expected lease-b to take over with a token greater than %d, got %d, %v", tokenA, tokenB, err)
+	}
+	if err := core.CheckFence(ctx, tokenA); !errors.Is(err, core.ErrLeaseLost) {
+		t.Fatalf("expected token %d to be fenced off, got %v", tokenA, err)
+	}
+}
+
+// TestLeaderTakeoverDuringDelivery checks that a replica taking over while the previous leader
+// may still have a transaction in flight waits for it, and that the previous leader can neither
+// broadcast again nor clear the new leader's mark.
+//
+// It needs the Redis configured in env.toml, without a running aggregator.
+func TestLeaderTakeoverDuringDelivery(t *testing.T) {
+	ctx := context.Background()
+	if leader, err := core.CurrentLeader(ctx); err != redis.Nil {
+		t.Skipf("the lease is held by %+v (%v), stop the aggregator to run this test", leader, err)
+	}
+	defer core.ReleaseLease(ctx, "takeover-a")
+	defer core.ReleaseLease(ctx, "takeover-b")
+	defer core.S.RedisConn.Del(ctx, core.PkLeaderBroadcast)
+
+	tokenA, err := core.AcquireLease(ctx, "takeover-a", time.Minute)
+	if err != nil || tokenA == 0 {
+		t.Fatalf("expected takeover-a to acquire the lease, got %d, %v", tokenA, err)
+	}
+	// takeover-a broadcasts, then loses the lease before its transaction is confirmed
+	if err := core.BeginBroadcast(ctx, tokenA, 2*time.Second); err != nil {
+		t.Fatalf("expected token %d to broadcast, got %v", tokenA, err)
+	}
+	if err := core.ReleaseLease(ctx, "takeover-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenB, err := core.AcquireLease(ctx, "takeover-b", time.Minute)
+	if err != nil || tokenB <= tokenA {
+		t.Fatalf("expected takeover-b to take over with a token greater than %d, got %d, %v", tokenA, tokenB, err)
+	}
+	if err := core.BeginBroadcast(ctx, tokenA, time.Minute); !errors.Is(err, core.ErrLeaseLost) {
+		t.Fatalf("expected token %d to be fenced off, got %v", tokenA, err)
+	}
+	waited, err := core.AwaitBroadcast(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if waited < time.Second {
+		t.Fatalf("expected takeover-b to wait for the transaction in flight, waited %s", waited)
+	}
+
+	// the new leader's mark outlives the previous leader's late EndBroadcast
+	if err := core.BeginBroadcast(ctx, tokenB, time.Minute); err != nil {
+		t.Fatalf("expected token %d to broadcast, got %v", tokenB, err)
+	}
+	if err := core.EndBroadcast(ctx, tokenA); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := core.S.RedisConn.Get(ctx, core.PkLeaderBroadcast).Int64(); err != nil || current != tokenB {
+		t.Fatalf("expected the mark of token %d, got %d, %v", tokenB, current, err)
+	}
+	if err := core.EndBroadcast(ctx, tokenB); err != nil {
+		t.Fatal(err)
+	}
+	if waited, err := core.AwaitBroadcast(ctx); err != nil || waited > time.Second {
+		t.Fatalf("expected no transaction in flight, waited %s, %v", waited, err)
+	}
+}
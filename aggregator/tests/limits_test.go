@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/api"
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
+
+// TestBodyLimit checks that submissions larger than [limits] bodyLimit are rejected before
+// they are parsed, with and without a Content-Length.
+//
+// t is the testing object provided by Go's testing package.
+func TestBodyLimit(t *testing.T) {
+	router := gin.New()
+	api.SetupRoutes(router)
+	rejected := svc.METRICS.RejectedRequests.WithLabelValues(api.RejectBodyTooLarge)
+	before := testutil.ToFloat64(rejected)
+
+	body := bytes.Repeat([]byte(" "), int(core.C.Limits.BodyLimit)+1)
+	req, _ := http.NewRequest("POST", "/api/aggregator", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	// a chunked body is cut off while it is read
+	req, _ = http.NewRequest("POST", "/api/aggregator", bytes.NewReader(body))
+	req.ContentLength = -1
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status code %d for a chunked body, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+
+	if got := testutil.ToFloat64(rejected) - before; got != 2 {
+		t.Fatalf("expected 2 rejected requests, got %v", got)
+	}
+}
+
+// TestForgedSubmissionsKeepTokens checks that submissions rejected before or by the signature
+// check do not spend the [limits] pubKeyRate tokens of the public key they name.
+//
+// t is the testing object provided by Go's testing package.
+func TestForgedSubmissionsKeepTokens(t *testing.T) {
+	if core.C.Limits.PubKeyRate <= 0 {
+		t.Skip("[limits] pubKeyRate is disabled")
+	}
+	ctx := context.Background()
+	victim := base64.StdEncoding.EncodeToString(secp256k1.GenPrivKey().PubKey().Bytes())
+	forged := []api.Payload{
+		// a stale timestamp
+		{Version: 1, TaskId: 1, Result: "true", Timestamp: 1, Nonce: 1, Signature: "forged", PubKey: victim, Role: core.RoleAttester},
+		// an unknown role
+		{Version: 1, TaskId: 1, Result: "true", Timestamp: time.Now().Unix(), Nonce: 1, Signature: "forged", PubKey: victim, Role: "observer"},
+		// an invalid result
+		{Version: 1, TaskId: 1, Result: "maybe", Timestamp: time.Now().Unix(), Nonce: 1, Signature: "forged", PubKey: victim, Role: core.RoleAttester},
+		// an invalid signature
+		{Version: 1, TaskId: 1, Result: "true", Timestamp: time.Now().Unix(), Nonce: 1, Signature: "forged", PubKey: victim, Role: core.RoleAttester},
+	}
+
+	// far more forged submissions than the bucket holds are all rejected as invalid
+	for i := 0; i < 3*core.C.Limits.PubKeyBurst; i++ {
+		payload := forged[i%len(forged)]
+		_, err := api.Submit(ctx, &payload)
+		var submitErr *api.SubmitError
+		if !errors.As(err, &submitErr) || submitErr.Status != http.StatusBadRequest {
+			t.Fatalf("submission %d: expected status code %d, got %v", i, http.StatusBadRequest, err)
+		}
+	}
+}
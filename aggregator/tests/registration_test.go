@@ -0,0 +1,42 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
+
+// TestRegistrationCache checks that cached registrations are answered without querying the
+// BVS directory, and are counted as cache hits.
+//
+// It needs the Redis and the chain configured in env.toml.
+func TestRegistrationCache(t *testing.T) {
+	ctx := context.Background()
+	registered := fmt.Sprintf("registration-registered-%d", time.Now().UnixNano())
+	unregistered := fmt.Sprintf("registration-unregistered-%d", time.Now().UnixNano())
+	defer core.S.RedisConn.Del(ctx, core.PkOperatorRegistration+registered, core.PkOperatorRegistration+unregistered)
+
+	core.S.RedisConn.Set(ctx, core.PkOperatorRegistration+registered, "registered", time.Minute)
+	core.S.RedisConn.Set(ctx, core.PkOperatorRegistration+unregistered, "unregistered", time.Minute)
+
+	hits := testutil.ToFloat64(svc.METRICS.RegistrationCacheHits)
+	misses := testutil.ToFloat64(svc.METRICS.RegistrationCacheMisses)
+	if ok, err := svc.MONITOR.VerifyOperator(ctx, registered); err != nil || !ok {
+		t.Fatalf("expected cached operator to be registered, got %v, %v", ok, err)
+	}
+	if ok, err := svc.MONITOR.VerifyOperator(ctx, unregistered); err != nil || ok {
+		t.Fatalf("expected cached operator to be unregistered, got %v, %v", ok, err)
+	}
+	if got := testutil.ToFloat64(svc.METRICS.RegistrationCacheHits) - hits; got != 2 {
+		t.Fatalf("expected 2 cache hits, got %v", got)
+	}
+	if got := testutil.ToFloat64(svc.METRICS.RegistrationCacheMisses) - misses; got != 0 {
+		t.Fatalf("expected no cache misses, got %v", got)
+	}
+}
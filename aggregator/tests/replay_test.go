@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/replay"
+)
+
+// TestRedisNonceStore checks that the shared nonce store only accepts increasing nonces, also
+// across nonces of different lengths, which it compares as decimal strings.
+//
+// It needs the Redis configured in env.toml.
+func TestRedisNonceStore(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	operator := fmt.Sprintf("nonce-operator-%d", 12_000_000+rand.Intn(1_000_000))
+	defer core.S.RedisConn.Del(ctx, replay.PkOperatorNonce+operator)
+
+	store := replay.NewRedisNonceStore(core.S.RedisConn)
+	steps := []struct {
+		nonce uint64
+		ok    bool
+	}{
+		{9, true},
+		{10, true}, // one digit more
+		{9, false}, // one digit less
+		{10, false},
+		{99, true},
+		{100, true},
+		{1_700_000_000_000_000_000, true}, // a wall-clock nonce in nanoseconds
+		{1_699_999_999_999_999_999, false},
+		{1_700_000_000_000_000_001, true},
+		{math.MaxUint64 - 1, true}, // beyond 2^53, where Lua numbers lose precision
+		{math.MaxUint64 - 2, false},
+		{math.MaxUint64, true},
+		{math.MaxUint64, false},
+	}
+	for i, step := range steps {
+		ok, err := store.Advance(ctx, operator, step.nonce)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if ok != step.ok {
+			t.Fatalf("step %d: expected nonce %d to be accepted %v, got %v", i, step.nonce, step.ok, ok)
+		}
+	}
+	last, err := core.S.RedisConn.Get(ctx, replay.PkOperatorNonce+operator).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != fmt.Sprint(uint64(math.MaxUint64)) {
+		t.Fatalf("expected the greatest nonce to be stored, got %s", last)
+	}
+}
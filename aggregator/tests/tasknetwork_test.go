@@ -0,0 +1,43 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
+
+// TestTaskType checks that a task's type is the network cached from the StateBank, and that
+// only configured networks are accepted.
+//
+// It needs the Redis configured in env.toml.
+func TestTaskType(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(11_000_000 + rand.Intn(1_000_000))
+	key := fmt.Sprintf("%s%d", core.PkTaskNetwork, taskId)
+	defer core.S.RedisConn.Del(ctx, key)
+
+	if err := core.S.RedisConn.Set(ctx, key, "bitcoin", time.Minute).Err(); err != nil {
+		t.Fatal(err)
+	}
+	taskType, err := svc.MONITOR.TaskType(ctx, taskId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if taskType != "bitcoin" {
+		t.Fatalf("expected the cached network, got %s", taskType)
+	}
+
+	if !core.KnownTaskType(core.DefaultTaskType) {
+		t.Fatal("expected the default type to be known")
+	}
+	if core.KnownTaskType(fmt.Sprintf("unknown-%d", taskId)) {
+		t.Fatal("expected a network without a policy to be unknown")
+	}
+}
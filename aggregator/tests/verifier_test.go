@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+	"github.com/satlayer/hello-world-bvs/aggregator/svc"
+)
+
+// TestVerifierCheck checks performer blocks against a fake CometBFT RPC.
+func TestVerifierCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			fmt.Fprint(w, `{"result":{"sync_info":{"latest_block_height":"105"}}}`)
+		case "/block":
+			fmt.Fprintf(w, `{"result":{"block_id":{"hash":"HASH%s"}}}`, r.URL.Query().Get("height"))
+		}
+	}))
+	defer srv.Close()
+
+	verifier, err := svc.NewVerifier([]core.Network{{Name: "cosmos", Kind: "cometbft", Endpoints: []string{srv.URL}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		taskType string
+		result   string
+		verdict  string
+	}{
+		{"cosmos", "100-HASH100", core.CheckValid},
+		{"cosmos", "100-hash100", core.CheckValid},
+		{"cosmos", "100-HASH101", core.CheckInvalid},
+		{"cosmos", "90-HASH90", core.CheckInvalid},
+		{"cosmos", "abc-HASH100", core.CheckInvalid},
+		{core.DefaultTaskType, "100-HASH100", core.CheckError},
+	}
+	for i, tc := range cases {
+		check := verifier.Check(context.Background(), tc.taskType, tc.result)
+		if check.Verdict != tc.verdict {
+			t.Fatalf("case %d: expected %s, got %+v", i, tc.verdict, check)
+		}
+	}
+
+	if _, err := svc.NewVerifier([]core.Network{{Name: "cosmos", Endpoints: []string{srv.URL}}, {Name: "cosmos", Endpoints: []string{srv.URL}}}); err == nil {
+		t.Fatal("expected duplicate networks to be rejected")
+	}
+}
+
+// TestRecordVoteVerification checks that the aggregator's verdict breaks ties and vetoes
+// approvals, and is recorded with the task.
+//
+// It needs the Redis configured in env.toml.
+func TestRecordVoteVerification(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	base := uint64(10_000_000 + rand.Intn(1_000_000))
+
+	yes := core.TaskSubmission{Address: "verify-yes", Result: "true", Role: core.RoleAttester}
+	no := core.TaskSubmission{Address: "verify-no", Result: "false", Role: core.RoleAttester}
+	cases := []struct {
+		mode      string
+		verdict   string
+		attesters []core.TaskSubmission
+		status    string
+		decidedBy string
+	}{
+		// a split vote waits for more attesters unless the verdict decides it
+		{core.VerifyTieBreak, core.CheckValid, []core.TaskSubmission{yes, no}, core.VoteApproved, "verifier"},
+		{core.VerifyTieBreak, core.CheckInvalid, []core.TaskSubmission{yes, no}, core.VoteRejected, "verifier"},
+		{core.VerifyTieBreak, core.CheckError, []core.TaskSubmission{yes, no}, core.VotePending, ""},
+		{core.VerifyOff, core.CheckValid, []core.TaskSubmission{yes, no}, core.VotePending, ""},
+		// an approval stands unless the verdict is invalid
+		{core.VerifyVeto, core.CheckInvalid, []core.TaskSubmission{yes}, core.VoteRejected, "verifier"},
+		{core.VerifyVeto, core.CheckValid, []core.TaskSubmission{yes}, core.VoteApproved, ""},
+		{core.VerifyVeto, core.CheckError, []core.TaskSubmission{yes}, core.VoteApproved, ""},
+		{core.VerifyVeto, core.CheckValid, []core.TaskSubmission{no}, core.VoteRejected, ""},
+	}
+	for i, tc := range cases {
+		taskId := base + uint64(i)
+		defer cleanupTask(ctx, taskId)
+
+		policy := core.ConsensusPolicy{MinimumAttesters: len(tc.attesters), Threshold: 66, MinimumStake: "0", Quorum: core.QuorumByCount, Tie: core.TiePending, Verification: tc.mode}
+		performer := core.TaskSubmission{
+			Address: "verify-performer", Result: "100-ABCD", Role: core.RolePerformer,
+			Check: &core.PerformerCheck{Verdict: tc.verdict, CheckedAt: time.Now().Unix()},
+		}
+		if _, err := core.RecordVote(ctx, taskId, &performer, policy, testDeadline()); err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		var outcome core.VoteOutcome
+		for _, attester := range tc.attesters {
+			attester := attester
+			var err error
+			if outcome, err = core.RecordVote(ctx, taskId, &attester, policy, testDeadline()); err != nil {
+				t.Fatalf("case %d: %v", i, err)
+			}
+		}
+		if outcome.Status != tc.status {
+			t.Fatalf("case %d: expected %s, got %+v", i, tc.status, outcome)
+		}
+
+		verification := loadVerification(t, ctx, taskId)
+		if check := verification.Performer.Check; check == nil || check.Verdict != tc.verdict {
+			t.Fatalf("case %d: verdict not recorded, got %+v", i, check)
+		}
+		if tc.status == core.VotePending {
+			continue
+		}
+		if verification.Consensus == nil || verification.Consensus.DecidedBy != tc.decidedBy {
+			t.Fatalf("case %d: expected the consensus to be decided by %q, got %+v", i, tc.decidedBy, verification.Consensus)
+		}
+	}
+}
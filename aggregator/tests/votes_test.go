@@ -0,0 +1,383 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/rand"
+
+	"github.com/satlayer/hello-world-bvs/aggregator/core"
+)
+
+// TestRecordVoteConcurrent fires parallel submissions at the vote store and checks that
+// none of them is lost or counted twice.
+//
+// It needs the Redis configured in env.toml.
+func TestRecordVoteConcurrent(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(1_000_000 + rand.Intn(1_000_000))
+	defer cleanupTask(ctx, taskId)
+
+	const attesters = 300
+	var wg sync.WaitGroup
+	errs := make(chan error, attesters)
+	for i := 0; i < attesters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outcome, err := core.RecordVote(ctx, taskId, &core.TaskSubmission{
+				Address:   fmt.Sprintf("attester%d", i),
+				Result:    "true",
+				Timestamp: time.Now().Unix(),
+				Role:      core.RoleAttester,
+				Stake:     "1",
+			}, core.DefaultConsensusPolicy, testDeadline())
+			if err == nil && outcome.Status != core.VoteRecorded {
+				err = fmt.Errorf("attester %d: unexpected status %s before the performer submitted", i, outcome.Status)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every vote must have survived the concurrent writes
+	verification := loadVerification(t, ctx, taskId)
+	if len(verification.Attesters) != attesters {
+		t.Fatalf("expected %d attesters, got %d", attesters, len(verification.Attesters))
+	}
+
+	// the same performer submitting in parallel is accepted exactly once and finishes the task
+	const performers = 100
+	var accepted, finished, duplicates int
+	var mu sync.Mutex
+	for i := 0; i < performers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			outcome, err := core.RecordVote(ctx, taskId, &core.TaskSubmission{
+				Address:   "performer",
+				Result:    "100-ABCD",
+				Timestamp: time.Now().Unix(),
+				Role:      core.RolePerformer,
+			}, core.DefaultConsensusPolicy, testDeadline())
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				accepted++
+				if outcome.Status != core.VoteApproved || outcome.Attesters != attesters || outcome.TotalStake != fmt.Sprint(attesters) {
+					t.Errorf("unexpected outcome %+v", outcome)
+				}
+			case errors.Is(err, core.ErrTaskFinished):
+				finished++
+			case errors.Is(err, core.ErrPerformerAlreadySubmitted):
+				duplicates++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted != 1 || finished+duplicates != performers-1 {
+		t.Fatalf("expected exactly one accepted performer, got %d accepted, %d finished, %d duplicates", accepted, finished, duplicates)
+	}
+
+	// the task is queued exactly once
+	queued, err := core.S.RedisConn.LRange(ctx, core.PkTaskQueue, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	for _, item := range queued {
+		var task core.Task
+		if json.Unmarshal([]byte(item), &task) == nil && task.TaskId == taskId {
+			count++
+			if task.TaskResult.Operator != "performer" || task.TaskResult.Result != 1 {
+				t.Errorf("unexpected queued task %+v", task)
+			}
+		}
+	}
+	if count != 1 {
+		t.Fatalf("expected the task to be queued once, got %d", count)
+	}
+
+	// late attesters are rejected once the task is finished
+	if _, err := core.RecordVote(ctx, taskId, &core.TaskSubmission{Address: "late", Result: "false", Role: core.RoleAttester, Stake: "1"}, core.DefaultConsensusPolicy, testDeadline()); !errors.Is(err, core.ErrTaskFinished) {
+		t.Fatalf("expected ErrTaskFinished, got %v", err)
+	}
+}
+
+// TestRecordVoteStakeWeighted checks that votes are weighted by stake, that stakes are summed
+// exactly and that consensus waits for the minimum total stake.
+//
+// It needs the Redis configured in env.toml.
+func TestRecordVoteStakeWeighted(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(2_000_000 + rand.Intn(1_000_000))
+	defer cleanupTask(ctx, taskId)
+
+	policy := core.ConsensusPolicy{MinimumAttesters: 1, Threshold: 66, MinimumStake: "1000", Quorum: core.QuorumByStake, Tie: core.TiePending}
+	steps := []struct {
+		submission core.TaskSubmission
+		status     string
+	}{
+		{core.TaskSubmission{Address: "performer", Result: "100-ABCD", Role: core.RolePerformer}, core.VoteRecorded},
+		// below the minimum stake, even though every vote is positive
+		{core.TaskSubmission{Address: "small1", Result: "true", Role: core.RoleAttester, Stake: "100"}, core.VotePending},
+		{core.TaskSubmission{Address: "small2", Result: "true", Role: core.RoleAttester, Stake: "100"}, core.VotePending},
+		// one attester with most of the stake outweighs the head count
+		{core.TaskSubmission{Address: "whale", Result: "false", Role: core.RoleAttester, Stake: "1000000000000000000000"}, core.VoteRejected},
+	}
+	for i, step := range steps {
+		submission := step.submission
+		outcome, err := core.RecordVote(ctx, taskId, &submission, policy, testDeadline())
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if outcome.Status != step.status {
+			t.Fatalf("step %d: expected %s, got %+v", i, step.status, outcome)
+		}
+	}
+
+	verification := loadVerification(t, ctx, taskId)
+	if verification.Consensus == nil || verification.Consensus.Result != 0 {
+		t.Fatalf("expected a rejected consensus, got %+v", verification.Consensus)
+	}
+	if w := verification.Consensus.Weights["whale"]; w != "1000000000000000000000" {
+		t.Fatalf("expected the whale's stake to be recorded exactly, got %s", w)
+	}
+	// far beyond the precision of a double
+	if verification.Consensus.TotalStake != "1000000000000000000200" || verification.Consensus.PositiveStake != "200" {
+		t.Fatalf("expected the stakes to be summed exactly, got %+v", verification.Consensus)
+	}
+}
+
+// TestRecordVoteCountQuorumTie checks head-count quorum, the tie policy and that all
+// submissions of a task must target the same network.
+//
+// It needs the Redis configured in env.toml.
+func TestRecordVoteCountQuorumTie(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	taskId := uint64(3_000_000 + rand.Intn(1_000_000))
+	defer cleanupTask(ctx, taskId)
+
+	policy := core.ConsensusPolicy{MinimumAttesters: 2, Threshold: 66, MinimumStake: "0", Quorum: core.QuorumByCount, Tie: core.TieApprove}
+	steps := []struct {
+		submission core.TaskSubmission
+		status     string
+		err        error
+	}{
+		{core.TaskSubmission{Address: "performer", Result: "100-ABCD", Role: core.RolePerformer, TaskType: "ethereum"}, core.VoteRecorded, nil},
+		{core.TaskSubmission{Address: "other", Result: "true", Role: core.RoleAttester, TaskType: "bitcoin"}, "", core.ErrTaskTypeMismatch},
+		// stake is ignored in count quorum
+		{core.TaskSubmission{Address: "whale", Result: "false", Role: core.RoleAttester, TaskType: "ethereum", Stake: "1000000"}, core.VoteRecorded, nil},
+		// one vote each way is a tie, which this policy approves
+		{core.TaskSubmission{Address: "small", Result: "true", Role: core.RoleAttester, TaskType: "ethereum"}, core.VoteApproved, nil},
+	}
+	for i, step := range steps {
+		submission := step.submission
+		outcome, err := core.RecordVote(ctx, taskId, &submission, policy, testDeadline())
+		if !errors.Is(err, step.err) {
+			t.Fatalf("step %d: expected %v, got %v", i, step.err, err)
+		}
+		if err == nil && outcome.Status != step.status {
+			t.Fatalf("step %d: expected %s, got %+v", i, step.status, outcome)
+		}
+	}
+}
+
+// TestExpireTask checks that tasks past their deadline are finalized with the outcome that
+// explains why consensus was not reached, and that non-responding operators are recorded.
+//
+// It needs the Redis configured in env.toml.
+func TestExpireTask(t *testing.T) {
+	ctx := context.Background()
+	rand.Seed(uint64(time.Now().UnixNano()))
+	base := uint64(4_000_000 + rand.Intn(1_000_000))
+
+	policy := core.ConsensusPolicy{MinimumAttesters: 2, Threshold: 66, MinimumStake: "0", Quorum: core.QuorumByCount, Tie: core.TiePending}
+	performer := core.TaskSubmission{Address: "expire-performer", Result: "100-ABCD", Role: core.RolePerformer}
+	yes := core.TaskSubmission{Address: "expire-yes", Result: "true", Role: core.RoleAttester, Stake: "10"}
+	no := core.TaskSubmission{Address: "expire-no", Result: "false", Role: core.RoleAttester, Stake: "10"}
+	// the attesters agree, but too little stake voted
+	stakePolicy := core.ConsensusPolicy{MinimumAttesters: 2, Threshold: 66, MinimumStake: "100", Quorum: core.QuorumByStake, Tie: core.TiePending}
+	cases := []struct {
+		policy      *core.ConsensusPolicy
+		submissions []core.TaskSubmission
+		outcome     string
+		result      int64
+		missing     []string
+	}{
+		{nil, []core.TaskSubmission{yes, no}, core.TimeoutPerformerMissing, core.ResultPerformerMissing, []string{"expire-assigned"}},
+		{nil, []core.TaskSubmission{performer, yes}, core.TimeoutInsufficientAttestations, core.ResultInsufficientAttestations, []string{"expire-no"}},
+		{nil, []core.TaskSubmission{performer, yes, no}, core.TimeoutSplitVote, core.ResultSplitVote, nil},
+		{&stakePolicy, []core.TaskSubmission{performer, yes, {Address: "expire-yes2", Result: "true", Role: core.RoleAttester, Stake: "10"}}, core.TimeoutInsufficientAttestations, core.ResultInsufficientAttestations, []string{"expire-no"}},
+	}
+	for i, tc := range cases {
+		taskId := base + uint64(i)
+		defer cleanupTask(ctx, taskId)
+		policy := policy
+		if tc.policy != nil {
+			policy = *tc.policy
+		}
+
+		// the deadline has already passed
+		deadline := core.Deadline{Time: time.Now().Add(-time.Second)}
+		for _, submission := range tc.submissions {
+			submission := submission
+			if _, err := core.RecordVote(ctx, taskId, &submission, policy, deadline); err != nil {
+				t.Fatalf("case %d: %v", i, err)
+			}
+		}
+
+		expired, err := core.ExpiredTasks(ctx, time.Now(), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !containsTask(expired, taskId) {
+			t.Fatalf("case %d: task %d not reported as expired", i, taskId)
+		}
+
+		timeout, err := core.ExpireTask(ctx, taskId, "expire-assigned", time.Now())
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+		}
+		if timeout == nil || timeout.Outcome != tc.outcome || timeout.Result != tc.result {
+			t.Fatalf("case %d: expected %s, got %+v", i, tc.outcome, timeout)
+		}
+		// other active operators are missing as well
+		for _, address := range tc.missing {
+			if !containsString(timeout.Missing, address) {
+				t.Fatalf("case %d: expected %s to be missing, got %v", i, address, timeout.Missing)
+			}
+		}
+		for _, submission := range tc.submissions {
+			if containsString(timeout.Missing, submission.Address) {
+				t.Fatalf("case %d: %s responded but is missing", i, submission.Address)
+			}
+		}
+
+		verification := loadVerification(t, ctx, taskId)
+		if verification.Timeout == nil || verification.Timeout.Outcome != tc.outcome {
+			t.Fatalf("case %d: timeout not recorded, got %+v", i, verification.Timeout)
+		}
+		// results the squaring contract counts against the performer are only recorded
+		if queued := isQueued(t, ctx, taskId); queued != core.SubmittableResult(tc.result) {
+			t.Fatalf("case %d: result %d queued %v", i, tc.result, queued)
+		}
+
+		// expiring twice or submitting after the deadline has no effect
+		if timeout, err := core.ExpireTask(ctx, taskId, "expire-assigned", time.Now()); err != nil || timeout != nil {
+			t.Fatalf("case %d: expected nothing to expire, got %+v, %v", i, timeout, err)
+		}
+		late := yes
+		late.Address = "expire-late"
+		if _, err := core.RecordVote(ctx, taskId, &late, policy, deadline); !errors.Is(err, core.ErrTaskFinished) {
+			t.Fatalf("case %d: expected ErrTaskFinished, got %v", i, err)
+		}
+	}
+}
+
+// isQueued reports whether a task waits in the delivery queue.
+func isQueued(t *testing.T, ctx context.Context, taskId uint64) bool {
+	queued, err := core.S.RedisConn.LRange(ctx, core.PkTaskQueue, 0, -1).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range queued {
+		var task core.Task
+		if json.Unmarshal([]byte(item), &task) == nil && task.TaskId == taskId {
+			return true
+		}
+	}
+	return false
+}
+
+func containsTask(taskIds []uint64, taskId uint64) bool {
+	for _, id := range taskIds {
+		if id == taskId {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func loadVerification(t *testing.T, ctx context.Context, taskId uint64) core.TaskVerification {
+	data, err := core.S.RedisConn.Get(ctx, fmt.Sprintf("%s%d", core.PkTaskVerification, taskId)).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verification core.TaskVerification
+	if err := json.Unmarshal([]byte(data), &verification); err != nil {
+		t.Fatal(err)
+	}
+	return verification
+}
+
+// testDeadline is a deadline the sweeper does not reach while a test runs.
+func testDeadline() core.Deadline {
+	return core.Deadline{Time: time.Now().Add(time.Hour)}
+}
+
+func cleanupTask(ctx context.Context, taskId uint64) {
+	verificationKey := fmt.Sprintf("%s%d", core.PkTaskVerification, taskId)
+	if data, err := core.S.RedisConn.Get(ctx, verificationKey).Result(); err == nil {
+		var verification core.TaskVerification
+		if json.Unmarshal([]byte(data), &verification) == nil {
+			// the test operators are made up, so their history can go as a whole
+			var operators []string
+			if verification.Performer != nil {
+				operators = append(operators, verification.Performer.Address)
+			}
+			for address := range verification.Attesters {
+				operators = append(operators, address)
+			}
+			for _, address := range operators {
+				core.S.RedisConn.ZRem(ctx, core.PkActiveOperators, address)
+				core.S.RedisConn.Del(ctx, core.PkOperatorTasks+address, core.PkOperatorStats+address)
+			}
+			if verification.Timeout != nil {
+				for _, address := range verification.Timeout.Missing {
+					core.S.RedisConn.ZRem(ctx, core.PkOperatorTasks+address, fmt.Sprint(taskId))
+				}
+			}
+		}
+	}
+	core.S.RedisConn.Del(ctx, verificationKey, fmt.Sprintf("%s%d", core.PkTaskFinished, taskId), fmt.Sprintf("%s%d", core.PkTaskHistory, taskId))
+	core.S.RedisConn.ZRem(ctx, core.PkTaskIndex, fmt.Sprint(taskId))
+	for _, status := range core.TaskStatuses {
+		core.S.RedisConn.ZRem(ctx, core.PkTaskIndex+":"+status, fmt.Sprint(taskId))
+	}
+	core.S.RedisConn.ZRem(ctx, core.PkTaskDeadlines, fmt.Sprint(taskId))
+	core.S.RedisConn.ZRem(ctx, core.PkTaskDeadlineHeights, fmt.Sprint(taskId))
+	queued, _ := core.S.RedisConn.LRange(ctx, core.PkTaskQueue, 0, -1).Result()
+	for _, item := range queued {
+		var task core.Task
+		if json.Unmarshal([]byte(item), &task) == nil && task.TaskId == taskId {
+			core.S.RedisConn.LRem(ctx, core.PkTaskQueue, 0, item)
+		}
+	}
+}
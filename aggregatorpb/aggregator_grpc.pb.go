@@ -0,0 +1,219 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: aggregator/v1/aggregator.proto
+
+package aggregatorpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	Aggregator_SubmitResult_FullMethodName     = "/aggregator.v1.Aggregator/SubmitResult"
+	Aggregator_GetPerformerData_FullMethodName = "/aggregator.v1.Aggregator/GetPerformerData"
+	Aggregator_StreamTaskEvents_FullMethodName = "/aggregator.v1.Aggregator/StreamTaskEvents"
+)
+
+// AggregatorClient is the client API for Aggregator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type AggregatorClient interface {
+	// SubmitResult submits a signed performer or attester result.
+	SubmitResult(ctx context.Context, in *SubmitResultRequest, opts ...grpc.CallOption) (*SubmitResultResponse, error)
+	// GetPerformerData returns the performer's result of a task, NOT_FOUND until it submitted.
+	GetPerformerData(ctx context.Context, in *GetPerformerDataRequest, opts ...grpc.CallOption) (*PerformerData, error)
+	// StreamTaskEvents streams the events of a task until the performer submitted or the
+	// stream timed out.
+	StreamTaskEvents(ctx context.Context, in *StreamTaskEventsRequest, opts ...grpc.CallOption) (Aggregator_StreamTaskEventsClient, error)
+}
+
+type aggregatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAggregatorClient(cc grpc.ClientConnInterface) AggregatorClient {
+	return &aggregatorClient{cc}
+}
+
+func (c *aggregatorClient) SubmitResult(ctx context.Context, in *SubmitResultRequest, opts ...grpc.CallOption) (*SubmitResultResponse, error) {
+	out := new(SubmitResultResponse)
+	err := c.cc.Invoke(ctx, Aggregator_SubmitResult_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aggregatorClient) GetPerformerData(ctx context.Context, in *GetPerformerDataRequest, opts ...grpc.CallOption) (*PerformerData, error) {
+	out := new(PerformerData)
+	err := c.cc.Invoke(ctx, Aggregator_GetPerformerData_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aggregatorClient) StreamTaskEvents(ctx context.Context, in *StreamTaskEventsRequest, opts ...grpc.CallOption) (Aggregator_StreamTaskEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Aggregator_ServiceDesc.Streams[0], Aggregator_StreamTaskEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aggregatorStreamTaskEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Aggregator_StreamTaskEventsClient interface {
+	Recv() (*TaskEvent, error)
+	grpc.ClientStream
+}
+
+type aggregatorStreamTaskEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *aggregatorStreamTaskEventsClient) Recv() (*TaskEvent, error) {
+	m := new(TaskEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AggregatorServer is the server API for Aggregator service.
+// All implementations must embed UnimplementedAggregatorServer
+// for forward compatibility
+type AggregatorServer interface {
+	// SubmitResult submits a signed performer or attester result.
+	SubmitResult(context.Context, *SubmitResultRequest) (*SubmitResultResponse, error)
+	// GetPerformerData returns the performer's result of a task, NOT_FOUND until it submitted.
+	GetPerformerData(context.Context, *GetPerformerDataRequest) (*PerformerData, error)
+	// StreamTaskEvents streams the events of a task until the performer submitted or the
+	// stream timed out.
+	StreamTaskEvents(*StreamTaskEventsRequest, Aggregator_StreamTaskEventsServer) error
+	mustEmbedUnimplementedAggregatorServer()
+}
+
+// UnimplementedAggregatorServer must be embedded to have forward compatible implementations.
+type UnimplementedAggregatorServer struct {
+}
+
+func (UnimplementedAggregatorServer) SubmitResult(context.Context, *SubmitResultRequest) (*SubmitResultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitResult not implemented")
+}
+func (UnimplementedAggregatorServer) GetPerformerData(context.Context, *GetPerformerDataRequest) (*PerformerData, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPerformerData not implemented")
+}
+func (UnimplementedAggregatorServer) StreamTaskEvents(*StreamTaskEventsRequest, Aggregator_StreamTaskEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTaskEvents not implemented")
+}
+func (UnimplementedAggregatorServer) mustEmbedUnimplementedAggregatorServer() {}
+
+// UnsafeAggregatorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AggregatorServer will
+// result in compilation errors.
+type UnsafeAggregatorServer interface {
+	mustEmbedUnimplementedAggregatorServer()
+}
+
+func RegisterAggregatorServer(s grpc.ServiceRegistrar, srv AggregatorServer) {
+	s.RegisterService(&Aggregator_ServiceDesc, srv)
+}
+
+func _Aggregator_SubmitResult_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitResultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AggregatorServer).SubmitResult(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aggregator_SubmitResult_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AggregatorServer).SubmitResult(ctx, req.(*SubmitResultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aggregator_GetPerformerData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPerformerDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AggregatorServer).GetPerformerData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Aggregator_GetPerformerData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AggregatorServer).GetPerformerData(ctx, req.(*GetPerformerDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Aggregator_StreamTaskEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamTaskEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AggregatorServer).StreamTaskEvents(m, &aggregatorStreamTaskEventsServer{stream})
+}
+
+type Aggregator_StreamTaskEventsServer interface {
+	Send(*TaskEvent) error
+	grpc.ServerStream
+}
+
+type aggregatorStreamTaskEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *aggregatorStreamTaskEventsServer) Send(m *TaskEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Aggregator_ServiceDesc is the grpc.ServiceDesc for Aggregator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Aggregator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aggregator.v1.Aggregator",
+	HandlerType: (*AggregatorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitResult",
+			Handler:    _Aggregator_SubmitResult_Handler,
+		},
+		{
+			MethodName: "GetPerformerData",
+			Handler:    _Aggregator_GetPerformerData_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamTaskEvents",
+			Handler:       _Aggregator_StreamTaskEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "aggregator/v1/aggregator.proto",
+}
@@ -1,7 +1,11 @@
 package core
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
 
 	"github.com/satlayer/satlayer-api/logger"
 
@@ -16,10 +20,51 @@ var L logger.Logger
 // No parameters.
 // No return values.
 func init() {
-	// load env.toml file
-	if _, err := toml.DecodeFile("env.toml", &C); err != nil {
+	// load env.toml file, or the one of this module when run elsewhere, e.g. by package tests
+	path := "env.toml"
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if _, currentFile, _, ok := runtime.Caller(0); ok {
+			path = filepath.Join(filepath.Dir(currentFile), "../env.toml")
+		}
+	}
+	if _, err := toml.DecodeFile(path, &C); err != nil {
 		panic(err)
 	}
 	fmt.Println("C: ", C)
 	L = logger.NewELKLogger(C.Chain.BvsHash)
 }
+
+// BvsList returns the BVS deployments the node serves.
+//
+// Without [[bvs]] entries the node serves the single BVS of the [chain], [aggregator] and
+// [checkpoint] sections, named "default".
+// Returns the deployments, or an error if names are missing or not unique.
+func (c *Config) BvsList() ([]Bvs, error) {
+	if len(c.Bvs) == 0 {
+		return []Bvs{{
+			Name:           "default",
+			BvsHash:        c.Chain.BvsHash,
+			BvsDriver:      c.Chain.BvsDriver,
+			StateBank:      c.Chain.StateBank,
+			Aggregator:     c.Aggregator.Url,
+			AggregatorGrpc: c.Aggregator.Grpc,
+			Checkpoint:     c.Checkpoint.File,
+		}}, nil
+	}
+	seen := make(map[string]bool)
+	list := make([]Bvs, 0, len(c.Bvs))
+	for _, b := range c.Bvs {
+		if b.Name == "" {
+			return nil, fmt.Errorf("bvs %s has no name", b.BvsHash)
+		}
+		if seen[b.Name] {
+			return nil, fmt.Errorf("duplicate bvs: %s", b.Name)
+		}
+		seen[b.Name] = true
+		if b.Checkpoint == "" {
+			b.Checkpoint = fmt.Sprintf("checkpoint.%s.json", b.Name)
+		}
+		list = append(list, b)
+	}
+	return list, nil
+}
@@ -7,6 +7,10 @@ type Config struct {
 	Owner      Owner
 	Aggregator Aggregator
 	Rpc        Rpc
+	Networks   []Network
+	Checkpoint Checkpoint
+	Metrics    Metrics
+	Bvs        []Bvs
 }
 
 type Chain struct {
@@ -26,36 +30,59 @@ type Owner struct {
 }
 
 type Aggregator struct {
-	Url string `json:"url"`
+	Url  string `json:"url"`
+	Grpc string `json:"grpc"` // gRPC address, submissions use the HTTP API if empty
+}
+
+type Metrics struct {
+	Host string `json:"host"`
+}
+
+type Checkpoint struct {
+	File      string `json:"file"`
+	MaxReplay int64  `json:"maxReplay"`
 }
 
 type Rpc struct {
-	Endpoint string `json:endpoint`
-}
-
-type StatusResponse struct {
-	JsonRPC string `json:"jsonrpc"`
-	Result  struct {
-		SyncInfo struct {
-			LatestBlockHeight string    `json:"latest_block_height"`
-			LatestBlockHash   string    `json:"latest_block_hash"`
-			LatestBlockTime   time.Time `json:"latest_block_time"`
-		} `json:"sync_info"`
-	} `json:"result"`
-}
-
-type BlockResponse struct {
-	JsonRPC string `json:"jsonrpc"`
-	Result  struct {
-		BlockID struct {
-			Hash string `json:"hash"`
-		} `json:"block_id"`
-		Block struct {
-			Header struct {
-				Height  string    `json:"height"`
-				Time    time.Time `json:"time"`
-				ChainID string    `json:"chain_id"`
-			} `json:"header"`
-		} `json:"block"`
-	} `json:"result"`
+	Endpoint  string   `json:"endpoint"`
+	Endpoints []string `json:"endpoints"`
+	Quorum    int      `json:"quorum"`
+	Kind      string   `json:"kind"`
+	MaxLag    int64    `json:"maxLag"`
+	Light     *Light   `json:"light"`
+}
+
+// Network is an additional upstream the node can serve and attest, selected per task.
+type Network struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Endpoint  string   `json:"endpoint"`
+	Endpoints []string `json:"endpoints"`
+	Quorum    int      `json:"quorum"`
+	MaxLag    int64    `json:"maxLag"`
+	Light     *Light   `json:"light"`
+}
+
+// Light enables CometBFT light-client verification of performer blocks on a cometbft network.
+//
+// The first endpoint is the light client's primary, the other endpoints are its witnesses
+// unless Witnesses is set.
+type Light struct {
+	ChainId        string        `json:"chainId"`
+	TrustedHeight  int64         `json:"trustedHeight"`
+	TrustedHash    string        `json:"trustedHash"`    // hex header hash at TrustedHeight
+	TrustingPeriod time.Duration `json:"trustingPeriod"` // e.g. "168h", well below the unbonding period
+	Witnesses      []string      `json:"witnesses"`
+	Store          string        `json:"store"` // directory of the verified headers, defaults to light/<network> next to the checkpoint
+}
+
+// Bvs is one BVS deployment the node serves with the operator key of [owner].
+type Bvs struct {
+	Name           string `json:"name"`
+	BvsHash        string `json:"bvsHash"`
+	BvsDriver      string `json:"bvsDriver"`
+	StateBank      string `json:"stateBank"`
+	Aggregator     string `json:"aggregator"`     // aggregator url
+	AggregatorGrpc string `json:"aggregatorGrpc"` // aggregator gRPC address, optional
+	Checkpoint     string `json:"checkpoint"`     // checkpoint file, defaults to checkpoint.<name>.json
 }
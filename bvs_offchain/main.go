@@ -2,22 +2,41 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
 	"github.com/satlayer/hello-world-bvs/bvs_offchain/node"
 )
 
 // main is the entry point of the program.
 //
-// It initializes a new node and runs it.
-// No parameters.
-// No return values.
-// main is the entry point of the program.
-//
-// It initializes a new node and runs it.
-// No parameters.
-// No return values.
+// It initializes a new node and runs it until SIGINT or SIGTERM is received.
+// Exits with a non-zero code if the node stops because of an error.
 func main() {
-	ctx := context.Background()
-	n := node.NewNode()
-	n.Run(ctx)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err := run(ctx)
+	stop()
+	if err != nil {
+		core.L.Error(fmt.Sprintf("Node stopped, due to {%s}", err))
+		fmt.Fprintf(os.Stderr, "node stopped: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run creates the node and runs it until ctx is cancelled.
+//
+// Returns nil on a clean shutdown, otherwise the error that stopped the node.
+func run(ctx context.Context) error {
+	n, err := node.NewNode(ctx)
+	if err == nil {
+		err = n.Run(ctx)
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
 }
@@ -0,0 +1,94 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/satlayer/satlayer-api/chainio/api"
+	"github.com/satlayer/satlayer-api/logger"
+	"google.golang.org/grpc"
+
+	"github.com/satlayer/hello-world-bvs/aggregatorpb"
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
+)
+
+// bvsNode is the indexer/driver pipeline of a single BVS.
+//
+// It shares the chain connection, key and upstream networks of its Node, and keeps its own
+// contracts, checkpoint, metrics and logs.
+type bvsNode struct {
+	*Node
+	cfg         core.Bvs
+	bvsContract string
+	stateBank   api.StateBank
+	checkpoint  *Checkpoint
+	metrics     *bvsMetrics
+	log         logger.Logger
+
+	// stateBankView tracks the StateBank events handed to stateBank
+	stateBankView *stateBankView
+
+	// aggregator is the client of the aggregator's gRPC API, nil to use the HTTP API
+	aggregator     aggregatorpb.AggregatorClient
+	aggregatorConn *grpc.ClientConn
+
+	stateBankIdx indexerState
+	driverIdx    indexerState
+	// taskHeight is the block height of the driver event of the task in flight, 0 for none
+	taskHeight atomic.Int64
+}
+
+// newBvsNode sets up the pipeline of a BVS.
+//
+// ctx bounds the retries of the chain queries made during setup.
+// n is the node the BVS runs on.
+// cfg is the BVS definition.
+// Returns the pipeline, or a FatalError if it cannot be set up.
+func newBvsNode(ctx context.Context, n *Node, cfg core.Bvs) (*bvsNode, error) {
+	var bvsContract string
+	err := retry(ctx, "get bvs info", defaultBackoff, func() error {
+		txResp, err := api.NewBVSDirectoryImpl(n.chainIO, core.C.Chain.BvsDirectory).GetBVSInfo(cfg.BvsHash)
+		if err != nil {
+			return err
+		}
+		bvsContract = txResp.BVSContract
+		return nil
+	})
+	if err != nil {
+		return nil, fatal(fmt.Sprintf("get bvs info of %s", cfg.Name), err)
+	}
+	checkpoint, err := LoadCheckpoint(cfg.Checkpoint, core.C.Checkpoint.MaxReplay)
+	if err != nil {
+		return nil, fatal(fmt.Sprintf("load checkpoint of %s", cfg.Name), err)
+	}
+
+	b := &bvsNode{
+		Node:        n,
+		cfg:         cfg,
+		bvsContract: bvsContract,
+		stateBank:   api.NewStateBankImpl(n.chainIO),
+		checkpoint:  checkpoint,
+		metrics:     n.metrics.forBvs(cfg.Name),
+		log:         logger.NewELKLogger(cfg.BvsHash),
+
+		stateBankView: newStateBankView(),
+	}
+	if cfg.AggregatorGrpc != "" {
+		b.aggregatorConn, b.aggregator, err = dialAggregator(cfg.AggregatorGrpc)
+		if err != nil {
+			return nil, fatal(fmt.Sprintf("connect aggregator of %s", cfg.Name), err)
+		}
+	}
+	return b, nil
+}
+
+// printf prints a progress line prefixed with the BVS name.
+func (b *bvsNode) printf(format string, a ...any) {
+	fmt.Printf("[%s] "+format, append([]any{b.cfg.Name}, a...)...)
+}
+
+// println prints a progress line prefixed with the BVS name.
+func (b *bvsNode) println(a ...any) {
+	fmt.Println(append([]any{fmt.Sprintf("[%s]", b.cfg.Name)}, a...)...)
+}
@@ -0,0 +1,201 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// checkpointSaveInterval is how often Advance writes the checkpoint at most, Flush writes the rest.
+const checkpointSaveInterval = time.Second
+
+// Checkpoint records how far the node has processed the driver events so a restart
+// can resume from there instead of the chain head.
+type Checkpoint struct {
+	// Height is the block height of the last processed driver event.
+	Height int64 `json:"height"`
+	// Answered maps task IDs the node has already answered, or gave up on, to the height of their event.
+	Answered map[string]int64 `json:"answered"`
+	// Failed maps task IDs that failed with a transient error to the height of their event.
+	// The node retries them while it runs and resumes from the lowest of them after a restart,
+	// until their event falls out of the replay window.
+	Failed map[string]int64 `json:"failed"`
+
+	mu        sync.Mutex
+	path      string
+	maxReplay int64
+	dirty     bool
+	savedAt   time.Time
+}
+
+// LoadCheckpoint reads the checkpoint file at path.
+//
+// A missing file yields an empty checkpoint.
+// maxReplay is how many blocks behind the last processed height a task is still replayed;
+// 0 means no cap.
+// Returns the checkpoint or an error if the file cannot be read or parsed.
+func LoadCheckpoint(path string, maxReplay int64) (*Checkpoint, error) {
+	cp := &Checkpoint{Answered: make(map[string]int64), Failed: make(map[string]int64), path: path, maxReplay: maxReplay}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %v", err)
+	}
+	if cp.Answered == nil {
+		cp.Answered = make(map[string]int64)
+	}
+	if cp.Failed == nil {
+		cp.Failed = make(map[string]int64)
+	}
+	return cp, nil
+}
+
+// StartHeight returns the height the indexers should resume from.
+//
+// That is the height of the earliest failed task, or the last processed height if none failed.
+// latest is the current chain height. At most maxReplay blocks before latest are replayed, and
+// failed tasks before them are dropped. Without a checkpoint the node starts at latest.
+func (cp *Checkpoint) StartHeight(latest int64) int64 {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cp.prune(latest)
+	// resume at the checkpoint height itself, a block can hold several driver events
+	start := cp.resumeHeight()
+	if start <= 0 || start > latest {
+		return latest
+	}
+	if cp.maxReplay > 0 && latest-start > cp.maxReplay {
+		return latest - cp.maxReplay
+	}
+	return start
+}
+
+// FailedTasks returns the tasks that failed with a transient error, by the height of their event.
+func (cp *Checkpoint) FailedTasks() map[string]int64 {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	failed := make(map[string]int64, len(cp.Failed))
+	for id, h := range cp.Failed {
+		failed[id] = h
+	}
+	return failed
+}
+
+// IsAnswered reports whether the node already answered taskId.
+func (cp *Checkpoint) IsAnswered(taskId string) bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	_, ok := cp.Answered[taskId]
+	return ok
+}
+
+// Advance records that the event at height was processed, and whether taskId is done.
+//
+// A task that is not done is kept as failed, and the checkpoint does not move past its height
+// until it is done or its event is more than maxReplay blocks behind the last processed height.
+// Answered entries older than the resume height are pruned, they can no longer be replayed.
+// The checkpoint is written at most once per checkpointSaveInterval, see Flush.
+// Returns the failed tasks dropped from the replay window, or an error if the checkpoint
+// cannot be persisted.
+func (cp *Checkpoint) Advance(height int64, taskId string, done bool) ([]string, error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if height > cp.Height {
+		cp.Height = height
+	}
+	if done {
+		cp.Answered[taskId] = height
+		delete(cp.Failed, taskId)
+	} else {
+		cp.Failed[taskId] = height
+	}
+	dropped := cp.prune(cp.Height)
+	cp.dirty = true
+	if time.Since(cp.savedAt) < checkpointSaveInterval {
+		return dropped, nil
+	}
+	return dropped, cp.save()
+}
+
+// Flush writes the checkpoint if Advance changed it since it was last written.
+func (cp *Checkpoint) Flush() error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	if !cp.dirty {
+		return nil
+	}
+	return cp.save()
+}
+
+// prune drops the failed tasks that are more than maxReplay blocks behind head, and the
+// answered tasks before the resume height.
+//
+// Returns the dropped failed tasks.
+func (cp *Checkpoint) prune(head int64) []string {
+	var dropped []string
+	if cp.maxReplay > 0 {
+		for id, h := range cp.Failed {
+			if h < head-cp.maxReplay {
+				delete(cp.Failed, id)
+				dropped = append(dropped, id)
+			}
+		}
+	}
+	resume := cp.resumeHeight()
+	for id, h := range cp.Answered {
+		if h < resume {
+			delete(cp.Answered, id)
+		}
+	}
+	return dropped
+}
+
+// resumeHeight returns the height of the earliest failed task, or Height if none failed.
+func (cp *Checkpoint) resumeHeight() int64 {
+	resume := cp.Height
+	for _, h := range cp.Failed {
+		if h < resume {
+			resume = h
+		}
+	}
+	return resume
+}
+
+// save writes the checkpoint atomically via a temporary file.
+func (cp *Checkpoint) save() error {
+	if cp.path == "" {
+		cp.dirty = false
+		return nil
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(cp.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create checkpoint dir: %v", err)
+	}
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	if err := os.Rename(tmp, cp.path); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+	cp.dirty = false
+	cp.savedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,80 @@
+package node
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	cp, err := LoadCheckpoint(path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), cp.StartHeight(100), "without a checkpoint the node starts at the head")
+
+	_, err = cp.Advance(10, "1", true)
+	require.NoError(t, err)
+	// a block can hold several driver events
+	_, err = cp.Advance(10, "2", false)
+	require.NoError(t, err)
+	require.NoError(t, cp.Flush())
+
+	loaded, err := LoadCheckpoint(path, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), loaded.Height)
+	assert.True(t, loaded.IsAnswered("1"))
+	assert.False(t, loaded.IsAnswered("2"))
+	assert.Equal(t, map[string]int64{"2": 10}, loaded.FailedTasks())
+}
+
+func TestCheckpointResumeHeight(t *testing.T) {
+	cp, err := LoadCheckpoint("", 0)
+	require.NoError(t, err)
+
+	_, err = cp.Advance(10, "1", false)
+	require.NoError(t, err)
+	_, err = cp.Advance(20, "2", true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), cp.StartHeight(30), "resumes at the earliest failed task")
+	assert.True(t, cp.IsAnswered("2"), "answered tasks after a failed one are kept")
+
+	// once the failed task is done, the node resumes at the last processed height
+	_, err = cp.Advance(10, "1", true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), cp.StartHeight(30))
+	assert.Empty(t, cp.FailedTasks())
+	assert.False(t, cp.IsAnswered("1"), "answered tasks before the resume height are pruned")
+	assert.True(t, cp.IsAnswered("2"))
+}
+
+func TestCheckpointMaxReplay(t *testing.T) {
+	cp, err := LoadCheckpoint("", 100)
+	require.NoError(t, err)
+
+	_, err = cp.Advance(50, "1", true)
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), cp.StartHeight(120))
+	assert.Equal(t, int64(900), cp.StartHeight(1000), "replays at most maxReplay blocks")
+
+	// a failed task is dropped once its event is more than maxReplay blocks behind
+	_, err = cp.Advance(60, "2", false)
+	require.NoError(t, err)
+	dropped, err := cp.Advance(150, "3", true)
+	require.NoError(t, err)
+	assert.Empty(t, dropped)
+	assert.Equal(t, int64(60), cp.StartHeight(150))
+	dropped, err = cp.Advance(170, "4", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, dropped)
+	assert.Empty(t, cp.FailedTasks())
+	assert.Equal(t, int64(170), cp.StartHeight(170))
+	assert.False(t, cp.IsAnswered("3"), "answered tasks no longer pinned by the failed one are pruned")
+
+	// a restart long after the failure drops it as well
+	_, err = cp.Advance(170, "5", false)
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), cp.StartHeight(1000))
+	assert.Empty(t, cp.FailedTasks())
+}
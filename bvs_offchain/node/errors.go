@@ -0,0 +1,64 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAlreadySubmitted is returned when the aggregator already holds this node's submission for a task.
+var ErrAlreadySubmitted = errors.New("already submitted to aggregator")
+
+// errNonceReused is returned when the aggregator accepted a greater nonce of this operator
+// first, e.g. from a concurrent task. The submission is retried with a fresh nonce.
+var errNonceReused = errors.New("aggregator rejected the nonce as used")
+
+// FatalError is an error the node cannot recover from, e.g. a broken keyring.
+// Run stops and returns it, and main exits with a non-zero code.
+type FatalError struct {
+	Op  string
+	Err error
+}
+
+func (e *FatalError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError is an error that retrying will not fix, e.g. a request the aggregator rejected.
+// It fails the current task but does not stop the node.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// fatal wraps err as a FatalError for the operation op.
+func fatal(op string, err error) error {
+	return &FatalError{Op: op, Err: err}
+}
+
+// permanent wraps err as a PermanentError.
+func permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// IsFatal reports whether err, or any error it wraps, is a FatalError.
+func IsFatal(err error) bool {
+	var fatalErr *FatalError
+	return errors.As(err, &fatalErr)
+}
+
+// isRetryable reports whether err is worth retrying.
+func isRetryable(err error) bool {
+	var permanentErr *PermanentError
+	return !IsFatal(err) && !errors.As(err, &permanentErr) && !errors.Is(err, ErrAlreadySubmitted)
+}
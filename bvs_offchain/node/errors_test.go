@@ -0,0 +1,45 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorClassification(t *testing.T) {
+	cause := errors.New("cause")
+	for _, tt := range []struct {
+		name      string
+		err       error
+		fatal     bool
+		retryable bool
+	}{
+		{"transient", cause, false, true},
+		{"deadline", context.DeadlineExceeded, false, true},
+		{"nonce reused", fmt.Errorf("%w: status 409", errNonceReused), false, true},
+		{"permanent", permanent(cause), false, false},
+		{"wrapped permanent", fmt.Errorf("submit: %w", permanent(cause)), false, false},
+		{"already submitted", fmt.Errorf("task 1: %w", ErrAlreadySubmitted), false, false},
+		{"fatal", fatal("sign", cause), true, false},
+		{"wrapped fatal", fmt.Errorf("bvs a: %w", fatal("sign", cause)), true, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.fatal, IsFatal(tt.err))
+			assert.Equal(t, tt.retryable, isRetryable(tt.err))
+		})
+	}
+}
+
+func TestErrorUnwrap(t *testing.T) {
+	cause := errors.New("keyring locked")
+	err := fatal("sign", cause)
+	assert.ErrorIs(t, err, cause)
+	assert.EqualError(t, err, "sign: keyring locked")
+
+	err = permanent(cause)
+	assert.ErrorIs(t, err, cause)
+	assert.EqualError(t, err, "keyring locked")
+}
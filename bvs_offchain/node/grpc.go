@@ -0,0 +1,164 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/satlayer/hello-world-bvs/aggregatorpb"
+	"github.com/satlayer/hello-world-bvs/payload"
+)
+
+// grpcTimeout bounds a single unary call to the aggregator's gRPC API.
+const grpcTimeout = 30 * time.Second
+
+// dialAggregator connects to the aggregator's gRPC API.
+//
+// The connection is established lazily on the first call, so the aggregator does not have
+// to be up when the node starts.
+// addr is the host:port of the aggregator's gRPC server.
+// Returns the connection and its client.
+func dialAggregator(addr string) (*grpc.ClientConn, aggregatorpb.AggregatorClient, error) {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dial aggregator %s: %v", addr, err)
+	}
+	return conn, aggregatorpb.NewAggregatorClient(conn), nil
+}
+
+// submitGrpc sends a signed submission over the aggregator's gRPC API.
+//
+// ctx is the context for the call.
+// submission is the signed submission.
+// Returns ErrAlreadySubmitted for a duplicate, a PermanentError if the aggregator rejected
+// the submission, or an error if the call failed.
+func (b *bvsNode) submitGrpc(ctx context.Context, submission payload.Submission) error {
+	ctx, cancel := context.WithTimeout(ctx, grpcTimeout)
+	defer cancel()
+
+	_, err := b.aggregator.SubmitResult(ctx, &aggregatorpb.SubmitResultRequest{
+		Version:   int32(submission.Version),
+		TaskId:    submission.TaskId,
+		Result:    submission.Result,
+		Timestamp: submission.Timestamp,
+		Nonce:     submission.Nonce,
+		Signature: submission.Signature,
+		PubKey:    submission.PubKey,
+		Role:      submission.Role,
+		Network:   submission.Network,
+	})
+	if err == nil {
+		return nil
+	}
+
+	b.printf("Error from aggregator: %s\n", err)
+	switch status.Code(err) {
+	case codes.AlreadyExists:
+		return ErrAlreadySubmitted
+	case codes.Aborted:
+		return fmt.Errorf("%w: %v", errNonceReused, err)
+	case codes.InvalidArgument, codes.PermissionDenied, codes.FailedPrecondition:
+		return permanent(fmt.Errorf("aggregator rejected submission: %v", err))
+	}
+	return fmt.Errorf("failed to send to aggregator: %v", err)
+}
+
+// streamPerformerDataGrpc waits for the performer's submission on the aggregator's gRPC task stream.
+//
+// ctx is the context for the stream.
+// taskId is the unique identifier of the task.
+// Returns the performer's data, errStreamUnavailable if the stream could not be opened,
+// or an error if the stream ended without the performer's data.
+func (b *bvsNode) streamPerformerDataGrpc(ctx context.Context, taskId string) (PerformerData, error) {
+	var performerData PerformerData
+
+	id, err := strconv.ParseUint(taskId, 10, 64)
+	if err != nil {
+		return performerData, permanent(fmt.Errorf("invalid task id %s: %v", taskId, err))
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := b.aggregator.StreamTaskEvents(ctx, &aggregatorpb.StreamTaskEventsRequest{TaskId: id})
+	if err != nil {
+		return performerData, fmt.Errorf("%w: %v", errStreamUnavailable, err)
+	}
+
+	b.printf("Subscribed to task %s, waiting for performer data...\n", taskId)
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return performerData, fmt.Errorf("task stream closed without performer data")
+		}
+		if err != nil {
+			if code := status.Code(err); code == codes.Unavailable || code == codes.Unimplemented {
+				return performerData, fmt.Errorf("%w: %v", errStreamUnavailable, err)
+			}
+			return performerData, fmt.Errorf("task stream interrupted: %v", err)
+		}
+		switch event.Type {
+		case "performer":
+			performerData = PerformerData{
+				Result:  event.GetPerformer().GetResult(),
+				Address: event.GetPerformer().GetAddress(),
+			}
+			b.printf("Got performer data for task %s: %s\n", taskId, performerData.Result)
+			return performerData, nil
+		case "timeout":
+			return performerData, fmt.Errorf("no performer data for task %s before the stream timed out", taskId)
+		}
+	}
+}
+
+// getPerformerDataGrpc polls the aggregator's gRPC API for the performer's submission of a task.
+//
+// It is the fallback when the gRPC task stream is unavailable.
+// ctx is the context for the calls.
+// taskId is the unique identifier of the task.
+// Returns the performer's data, or an error if it did not show up in time.
+func (b *bvsNode) getPerformerDataGrpc(ctx context.Context, taskId string) (PerformerData, error) {
+	// Retry configuration, same as the HTTP poll
+	maxRetries := 5
+	retryDelay := 3 * time.Second
+
+	var performerData PerformerData
+	id, err := strconv.ParseUint(taskId, 10, 64)
+	if err != nil {
+		return performerData, permanent(fmt.Errorf("invalid task id %s: %v", taskId, err))
+	}
+	for i := 0; i < maxRetries; i++ {
+		if i > 0 {
+			if err := sleep(ctx, retryDelay); err != nil {
+				return performerData, err
+			}
+			b.printf("Retry %d/%d for task %s\n", i+1, maxRetries, taskId)
+		}
+
+		callCtx, cancel := context.WithTimeout(ctx, grpcTimeout)
+		data, err := b.aggregator.GetPerformerData(callCtx, &aggregatorpb.GetPerformerDataRequest{TaskId: id})
+		cancel()
+		if status.Code(err) == codes.NotFound {
+			b.printf("No performer data yet for task %s, retrying...\n", taskId)
+			continue
+		}
+		if err != nil {
+			b.metrics.AggregatorErrors.Inc()
+			b.printf("Failed to get performer data: %v, retrying...\n", err)
+			continue
+		}
+
+		performerData = PerformerData{Result: data.Result, Address: data.Address}
+		b.printf("Got performer data for task %s: %s\n", taskId, performerData.Result)
+		return performerData, nil
+	}
+
+	return performerData, fmt.Errorf("failed to get performer data after %d retries", maxRetries)
+}
@@ -0,0 +1,123 @@
+package node
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/prober"
+)
+
+// Metrics are the operator node's Prometheus metrics.
+//
+// Task metrics are labelled with the name of the BVS they belong to.
+type Metrics struct {
+	TasksSeen          *prometheus.CounterVec
+	TasksPerformed     *prometheus.CounterVec
+	TasksAttested      *prometheus.CounterVec
+	ValidationFailures *prometheus.CounterVec
+	AggregatorErrors   *prometheus.CounterVec
+	UpstreamLatency    *prometheus.HistogramVec
+}
+
+// bvsMetrics are the task metrics of a single BVS.
+type bvsMetrics struct {
+	TasksSeen          prometheus.Counter
+	TasksPerformed     prometheus.Counter
+	TasksAttested      prometheus.Counter
+	ValidationFailures prometheus.Counter
+	AggregatorErrors   prometheus.Counter
+}
+
+// NewMetrics creates the node metrics and registers them with reg.
+//
+// reg is the registry served on /metrics.
+// Returns the metrics.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	const namespace, subsystem = "bvs_demo", "node"
+	labels := []string{"bvs"}
+	m := &Metrics{
+		TasksSeen: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "tasks_seen_total",
+			Help: "Tasks received from the driver contract.",
+		}, labels),
+		TasksPerformed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "tasks_performed_total",
+			Help: "Tasks this node performed and submitted to the aggregator.",
+		}, labels),
+		TasksAttested: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "tasks_attested_total",
+			Help: "Tasks this node attested and submitted to the aggregator.",
+		}, labels),
+		ValidationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "validation_failures_total",
+			Help: "Performer submissions this node found invalid.",
+		}, labels),
+		AggregatorErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name: "aggregator_errors_total",
+			Help: "Failed requests to the aggregator.",
+		}, labels),
+		UpstreamLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: subsystem,
+			Name:    "upstream_rpc_duration_seconds",
+			Help:    "Latency of upstream RPC reads.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"network", "upstream", "method", "status"}),
+	}
+	reg.MustRegister(
+		m.TasksSeen,
+		m.TasksPerformed,
+		m.TasksAttested,
+		m.ValidationFailures,
+		m.AggregatorErrors,
+		m.UpstreamLatency,
+	)
+	return m
+}
+
+// forBvs returns the task metrics of the BVS named name.
+func (m *Metrics) forBvs(name string) *bvsMetrics {
+	return &bvsMetrics{
+		TasksSeen:          m.TasksSeen.WithLabelValues(name),
+		TasksPerformed:     m.TasksPerformed.WithLabelValues(name),
+		TasksAttested:      m.TasksAttested.WithLabelValues(name),
+		ValidationFailures: m.ValidationFailures.WithLabelValues(name),
+		AggregatorErrors:   m.AggregatorErrors.WithLabelValues(name),
+	}
+}
+
+// instrumentedProber records the latency of every read of the wrapped prober.
+type instrumentedProber struct {
+	prober.Prober
+	network  string
+	upstream string
+	latency  *prometheus.HistogramVec
+}
+
+func (p *instrumentedProber) LatestBlock(ctx context.Context) (prober.Block, error) {
+	start := time.Now()
+	block, err := p.Prober.LatestBlock(ctx)
+	p.observe("latest_block", start, err)
+	return block, err
+}
+
+func (p *instrumentedProber) BlockAt(ctx context.Context, height int64) (prober.Block, error) {
+	start := time.Now()
+	block, err := p.Prober.BlockAt(ctx, height)
+	p.observe("block_at", start, err)
+	return block, err
+}
+
+func (p *instrumentedProber) observe(method string, start time.Time, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	p.latency.WithLabelValues(p.network, p.upstream, method, status).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,144 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/prober"
+)
+
+// DefaultNetwork is the name of the network configured in the [rpc] section.
+const DefaultNetwork = "default"
+
+type network struct {
+	name   string
+	prober *prober.QuorumProber
+	// verifier checks performer blocks; it is a light client if one is configured,
+	// otherwise the quorum prober.
+	verifier prober.Prober
+	maxLag   int64
+}
+
+// newNetworks builds a quorum prober for the [rpc] section and every [[networks]] entry.
+//
+// ctx bounds the startup of light clients.
+// metrics records the latency of every upstream read.
+// Returns the networks keyed by name, or an error if a network is misconfigured.
+func newNetworks(ctx context.Context, metrics *Metrics) (map[string]*network, error) {
+	networks := make(map[string]*network)
+	add := func(name, kind string, endpoints []string, quorum int, maxLag int64, lc *core.Light) error {
+		if _, exists := networks[name]; exists {
+			return fmt.Errorf("duplicate network: %s", name)
+		}
+		upstreams := make([]prober.Upstream, 0, len(endpoints))
+		for _, endpoint := range endpoints {
+			p, err := prober.NewProber(kind, endpoint)
+			if err != nil {
+				return fmt.Errorf("network %s: %v", name, err)
+			}
+			p = &instrumentedProber{Prober: p, network: name, upstream: endpoint, latency: metrics.UpstreamLatency}
+			upstreams = append(upstreams, prober.Upstream{Name: endpoint, Prober: p})
+		}
+		qp, err := prober.NewQuorumProber(upstreams, quorum)
+		if err != nil {
+			return fmt.Errorf("network %s: %v", name, err)
+		}
+		qp.OnDisagreement = func(upstream string, height int64, reason string) {
+			core.L.Error(fmt.Sprintf("Upstream {%s} of network {%s} disagreed at height {%d}: %s", upstream, name, height, reason))
+		}
+		if maxLag <= 0 {
+			maxLag = prober.DefaultMaxLag
+		}
+		var verifier prober.Prober = qp
+		if lc != nil {
+			if verifier, err = newLightVerifier(ctx, name, kind, endpoints, lc); err != nil {
+				return err
+			}
+		}
+		networks[name] = &network{name: name, prober: qp, verifier: verifier, maxLag: maxLag}
+		return nil
+	}
+
+	rpc := core.C.Rpc
+	if err := add(DefaultNetwork, rpc.Kind, endpointList(rpc.Endpoint, rpc.Endpoints), rpc.Quorum, rpc.MaxLag, rpc.Light); err != nil {
+		return nil, err
+	}
+	for _, nw := range core.C.Networks {
+		if err := add(nw.Name, nw.Kind, endpointList(nw.Endpoint, nw.Endpoints), nw.Quorum, nw.MaxLag, nw.Light); err != nil {
+			return nil, err
+		}
+	}
+	return networks, nil
+}
+
+// newLightVerifier starts the light client of a cometbft network.
+//
+// The first endpoint is the primary; the other endpoints are the witnesses unless lc lists them.
+// Returns the light client prober, or an error if it cannot be started.
+func newLightVerifier(ctx context.Context, name, kind string, endpoints []string, lc *core.Light) (prober.Prober, error) {
+	if kind != "" && kind != prober.KindCometBFT {
+		return nil, fmt.Errorf("network %s: light client verification requires kind %s", name, prober.KindCometBFT)
+	}
+	witnesses := lc.Witnesses
+	if len(witnesses) == 0 {
+		witnesses = endpoints[1:]
+	}
+	cfg := prober.LightConfig{
+		ChainId:        lc.ChainId,
+		Primary:        endpoints[0],
+		Witnesses:      witnesses,
+		TrustedHeight:  lc.TrustedHeight,
+		TrustedHash:    lc.TrustedHash,
+		TrustingPeriod: lc.TrustingPeriod,
+		StoreDir:       lc.Store,
+	}
+	if cfg.StoreDir == "" {
+		cfg.StoreDir = filepath.Join(filepath.Dir(core.C.Checkpoint.File), "light", name)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("network %s: %v", name, err)
+	}
+	var lp prober.Prober
+	err := retry(ctx, fmt.Sprintf("start light client of %s", name), defaultBackoff, func() (err error) {
+		lp, err = prober.NewLightProber(ctx, cfg)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("network %s: %v", name, err)
+	}
+	return lp, nil
+}
+
+// endpointList merges the single `endpoint` key with the `endpoints` list.
+func endpointList(endpoint string, endpoints []string) []string {
+	if endpoint == "" {
+		return endpoints
+	}
+	return append([]string{endpoint}, endpoints...)
+}
+
+// networkForTask resolves the network a task targets.
+//
+// The task creator may set the StateBank key "taskNetwork.<id>" to the name of a configured
+// network; tasks without it target the default network. The key only counts as missing once
+// the StateBank events are handled up to the task's driver event.
+// taskId is the unique identifier of the task.
+// height is the block height of the task's driver event.
+// Returns the network, errStateNotIndexed if the key may not be indexed yet, or a
+// PermanentError if the task names a network this node does not serve.
+func (b *bvsNode) networkForTask(taskId string, height int64) (*network, error) {
+	name, err := b.stateBankView.network(taskId, height)
+	if err != nil {
+		return nil, err
+	}
+	if name == "" {
+		name = DefaultNetwork
+	}
+	nw, ok := b.networks[name]
+	if !ok {
+		return nil, permanent(fmt.Errorf("network %s is not configured", name))
+	}
+	return nw, nil
+}
@@ -5,13 +5,17 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	rio "io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/satlayer/satlayer-api/chainio/types"
 	"github.com/satlayer/satlayer-api/logger"
@@ -19,26 +23,30 @@ import (
 
 	"github.com/satlayer/satlayer-api/chainio/io"
 
-	"github.com/satlayer/hello-world-bvs/aggregator/util"
 	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
-	"github.com/satlayer/satlayer-api/chainio/api"
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/prober"
+	"github.com/satlayer/hello-world-bvs/payload"
 	"github.com/satlayer/satlayer-api/chainio/indexer"
 )
 
-type Node struct {
-	bvsContract string
-	pubKeyStr   string
-	chainIO     io.ChainIO
-	stateBank   api.StateBank
-}
+// drainTimeout is how long an in-flight task may keep running after shutdown was requested.
+const drainTimeout = 30 * time.Second
 
-type Payload struct {
-	TaskId    int64  `json:"taskID"`
-	Result    string `json:"result"`
-	Timestamp int64  `json:"timestamp"`
-	Signature string `json:"signature"`
-	PubKey    string `json:"pubKey"`
-	Role      string `json:"role"`
+// failedRetryInterval is how often tasks that failed with a transient error are retried.
+const failedRetryInterval = time.Minute
+
+// Node is an operator node serving one or more BVS deployments with a single key.
+type Node struct {
+	pubKeyStr string
+	address   string
+	chainIO   io.ChainIO
+	networks  map[string]*network
+	registry  *prometheus.Registry
+	metrics   *Metrics
+	bvs       []*bvsNode
+	lastNonce atomic.Uint64
+	// keyring is the result of the latest signing probe, see probeKeyring
+	keyring atomic.Value
 }
 
 type PerformerData struct {
@@ -48,13 +56,10 @@ type PerformerData struct {
 
 // NewNode creates a new Node instance with the given configuration.
 //
-// It initializes a new Cosmos client, retrieves the account, and sets up the BVS contracts and state bank.
-// Returns a pointer to the newly created Node instance.
-// NewNode creates a new Node instance with the given configuration.
-//
-// It initializes a new Cosmos client, retrieves the account, and sets up the BVS contracts and state bank.
-// Returns a pointer to the newly created Node instance.
-func NewNode() *Node {
+// It initializes a new Cosmos client, retrieves the account, and sets up every configured BVS.
+// ctx bounds the retries of the chain queries made during setup.
+// Returns a pointer to the newly created Node instance, or a FatalError if the node cannot be set up.
+func NewNode(ctx context.Context) (*Node, error) {
 	elkLogger := logger.NewELKLogger("bvs_demo")
 	elkLogger.SetLogLevel("info")
 	reg := prometheus.NewRegistry()
@@ -66,369 +71,605 @@ func NewNode() *Node {
 		GasPriceAdjustmentRate: "1.1",
 	})
 	if err != nil {
-		panic(err)
+		return nil, fatal("create chain io", err)
 	}
 	chainIO, err = chainIO.SetupKeyring(core.C.Owner.KeyName, core.C.Owner.KeyringBackend)
 	if err != nil {
-		panic(err)
+		return nil, fatal("setup keyring", err)
 	}
 	account, err := chainIO.GetCurrentAccount()
 	if err != nil {
-		panic(err)
+		return nil, fatal("get current account", err)
 	}
 	pubKeyStr := base64.StdEncoding.EncodeToString(account.GetPubKey().Bytes())
-	txResp, err := api.NewBVSDirectoryImpl(chainIO, core.C.Chain.BvsDirectory).GetBVSInfo(core.C.Chain.BvsHash)
+	address := sdk.AccAddress(account.GetPubKey().Address()).String()
+	metrics := NewMetrics(reg)
+	networks, err := newNetworks(ctx, metrics)
 	if err != nil {
-		panic(err)
+		return nil, fatal("configure networks", err)
 	}
-	stateBank := api.NewStateBankImpl(chainIO)
 
-	return &Node{
-		bvsContract: txResp.BVSContract,
-		stateBank:   stateBank,
-		chainIO:     chainIO,
-		pubKeyStr:   pubKeyStr,
+	n := &Node{
+		chainIO:   chainIO,
+		pubKeyStr: pubKeyStr,
+		address:   address,
+		networks:  networks,
+		registry:  reg,
+		metrics:   metrics,
 	}
+	bvsList, err := core.C.BvsList()
+	if err != nil {
+		return nil, fatal("configure bvs", err)
+	}
+	n.keyring.Store(checkKeyring(chainIO))
+	for _, cfg := range bvsList {
+		b, err := newBvsNode(ctx, n, cfg)
+		if err != nil {
+			return nil, err
+		}
+		n.bvs = append(n.bvs, b)
+	}
+	return n, nil
 }
 
-// Run starts the node's main execution loop.
-//
-// ctx is the context for the Run function.
-// No return value.
-// Run starts the node's main execution loop.
+// Run starts the pipeline of every BVS concurrently.
 //
+// It returns when ctx is cancelled, after the in-flight tasks have drained, or when a fatal error
+// occurs in any BVS, in which case the others are stopped as well.
 // ctx is the context for the Run function.
-// No return value.
-func (n *Node) Run(ctx context.Context) {
-	// @reminder ask what this is doing?
-	if err := n.syncStateBank(ctx); err != nil {
-		panic(err)
+// Returns ctx.Err() on shutdown, or the error that stopped the node.
+func (n *Node) Run(ctx context.Context) error {
+	if core.C.Metrics.Host != "" {
+		go func() {
+			if err := n.serveMetrics(ctx); err != nil {
+				core.L.Error(fmt.Sprintf("Failed to serve metrics, due to {%s}", err))
+			}
+		}()
 	}
-	if err := n.monitorDriver(ctx); err != nil {
-		panic(err)
+
+	go n.probeKeyring(ctx, keyringProbeInterval)
+
+	return runAll(ctx, n.bvs, (*bvsNode).Run)
+}
+
+// runAll runs run for every BVS concurrently until all of them returned.
+//
+// The first error other than context.Canceled cancels the others.
+// ctx is the node context.
+// Returns ctx.Err() on shutdown, or the first error, prefixed with the name of its BVS.
+func runAll(ctx context.Context, bvs []*bvsNode, run func(*bvsNode, context.Context) error) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var wg sync.WaitGroup
+	for _, b := range bvs {
+		wg.Add(1)
+		go func(b *bvsNode) {
+			defer wg.Done()
+			if err := run(b, ctx); err != nil && !errors.Is(err, context.Canceled) {
+				cancel(fmt.Errorf("bvs %s: %w", b.cfg.Name, err))
+			}
+		}(b)
 	}
+	wg.Wait()
+	return context.Cause(ctx)
 }
 
-// syncStateBank synchronizes the state bank with the latest blockchain state.
+// Run runs the BVS pipeline until ctx is cancelled or a fatal error occurs.
 //
-// ctx is the context for the syncStateBank function.
-// Returns an error if the synchronization fails.
-// syncStateBank synchronizes the state bank with the latest blockchain state.
+// ctx is the node context.
+// Returns ctx.Err() on shutdown, or the error that stopped the pipeline.
+func (b *bvsNode) Run(ctx context.Context) error {
+	if b.aggregatorConn != nil {
+		defer b.aggregatorConn.Close()
+	}
+	// calcTask reads the task assignment from the StateBank, and waits for its events to reach the task
+	if err := b.syncStateBank(ctx); err != nil {
+		return err
+	}
+	return b.monitorDriver(ctx)
+}
+
+// syncStateBank starts synchronizing the state bank with the chain in the background.
 //
-// ctx is the context for the syncStateBank function.
-// Returns an error if the synchronization fails.
-func (n *Node) syncStateBank(ctx context.Context) (err error) {
-	res, err := n.chainIO.QueryNodeStatus(ctx)
+// The events are handed to the StateBank client through stateBankView, which tracks how far
+// they got, see networkForTask.
+// ctx is the context for the synchronization.
+// Returns a fatal error if the indexer cannot be started.
+func (b *bvsNode) syncStateBank(ctx context.Context) error {
+	latestBlock, err := b.startHeight(ctx)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	latestBlock := res.SyncInfo.LatestBlockHeight
-	idx := n.stateBank.Indexer(n.chainIO.GetClientCtx(), core.C.Chain.StateBank, n.bvsContract, latestBlock, []string{"wasm-UpdateState"}, 1, 10)
-	processingQueue, err := idx.Run(ctx)
+	idx := b.stateBank.Indexer(b.chainIO.GetClientCtx(), b.cfg.StateBank, b.bvsContract, latestBlock, []string{"wasm-UpdateState"}, 1, 10)
+	var processingQueue chan *indexer.Event
+	err = retry(ctx, "run state bank indexer", defaultBackoff, func() (err error) {
+		processingQueue, err = idx.Run(ctx)
+		return err
+	})
 	if err != nil {
-		panic(err)
+		return fatal("run state bank indexer", err)
 	}
+	events := make(chan *indexer.Event)
+	go b.stateBankView.forward(ctx, processingQueue, events)
 	go func() {
-		n.stateBank.EventHandler(processingQueue)
+		b.stateBank.EventHandler(events)
 	}()
-	ticker := time.NewTicker(time.Second * 2)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if idx.IsUpToDate {
-				return
-			}
-		}
-	}
-
-	return
+	// caught up while no event is queued and the task in flight does not wait for the StateBank
+	go b.stateBankIdx.watch(ctx, func() bool {
+		return len(processingQueue) == 0 && b.stateBankView.Height() >= b.taskHeight.Load()
+	}, indexerPollInterval)
+	return nil
 }
 
 // monitorDriver monitors the driver contract for events and performs actions based on the event type.
 //
+// Tasks are processed one at a time, and tasks that failed with a transient error are retried
+// every failedRetryInterval in between. On shutdown no new events are taken, and the task in
+// flight is given drainTimeout to finish before its context is cancelled too.
 // ctx is the context for the monitorDriver function.
-// Returns an error if there is an issue with the monitoring process.
-// monitorDriver monitors the driver contract for events and performs actions based on the event type.
-//
-// ctx is the context for the monitorDriver function.
-// Returns an error if there is an issue with the monitoring process.
-func (n *Node) monitorDriver(ctx context.Context) (err error) {
-	res, err := n.chainIO.QueryNodeStatus(ctx)
+// Returns ctx.Err() on shutdown, or a fatal error from the indexer or a task.
+func (b *bvsNode) monitorDriver(ctx context.Context) error {
+	latestBlock, err := b.startHeight(ctx)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	latestBlock := res.SyncInfo.LatestBlockHeight
-	fmt.Println("latestBlock: ", latestBlock)
+	b.println("latestBlock: ", latestBlock)
 	evtIndexer := indexer.NewEventIndexer(
-		n.chainIO.GetClientCtx(),
-		core.C.Chain.BvsDriver,
+		b.chainIO.GetClientCtx(),
+		b.cfg.BvsDriver,
 		latestBlock,
 		[]string{"wasm-ExecuteBVSOffchain"},
 		1,
 		10)
-	evtChain, err := evtIndexer.Run(ctx)
+	var evtChain chan *indexer.Event
+	err = retry(ctx, "run driver indexer", defaultBackoff, func() (err error) {
+		evtChain, err = evtIndexer.Run(ctx)
+		return err
+	})
 	if err != nil {
-		panic(err)
+		return fatal("run driver indexer", err)
 	}
-	fmt.Println("chain: ", evtChain)
-	for evt := range evtChain {
-		if evt.AttrMap["sender"] != n.bvsContract {
-			continue
+	go b.driverIdx.watch(ctx, func() bool { return len(evtChain) == 0 }, indexerPollInterval)
+	defer func() {
+		if err := b.checkpoint.Flush(); err != nil {
+			b.log.Error(fmt.Sprintf("Failed to save checkpoint, due to {%s}", err))
 		}
-		switch evt.EventType {
-		case "wasm-ExecuteBVSOffchain":
-			time.Sleep(5 * time.Second)
-			taskId := evt.AttrMap["task_id"]
-			fmt.Println("taskId: ", taskId)
-			if err := n.calcTask(taskId); err != nil {
-				fmt.Println("ExecuteBVSOffchain error: ", err)
+	}()
+	retryTicker := time.NewTicker(failedRetryInterval)
+	defer retryTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-retryTicker.C:
+			if err := b.retryFailed(ctx); err != nil {
+				return err
+			}
+		case evt, ok := <-evtChain:
+			if !ok {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return fatal("monitor driver", errors.New("event indexer stopped"))
+			}
+			if err := b.handleDriverEvent(ctx, evt); err != nil {
+				return err
 			}
-		default:
-			fmt.Println("unhandled event: ", evt.EventType)
 		}
 	}
-	return
 }
 
-// calcTask calculates the task result and sends it to the aggregator.
+// handleDriverEvent processes a single driver event and advances the checkpoint.
+//
+// ctx is the node context; the task runs on a context detached from it so it can drain on shutdown.
+// evt is the event emitted by the driver contract.
+// Returns only fatal errors; task failures are logged.
+func (b *bvsNode) handleDriverEvent(ctx context.Context, evt *indexer.Event) error {
+	if evt.AttrMap["sender"] != b.bvsContract {
+		return nil
+	}
+	switch evt.EventType {
+	case "wasm-ExecuteBVSOffchain":
+		taskId := evt.AttrMap["task_id"]
+		b.println("taskId: ", taskId)
+		b.metrics.TasksSeen.Inc()
+		if b.checkpoint.IsAnswered(taskId) {
+			b.printf("Task %s already answered, skipping\n", taskId)
+			b.stateBankView.forget(taskId)
+			return nil
+		}
+		// give the StateBank indexer time to pick up the task assignment
+		return b.processTask(ctx, evt.BlockHeight, taskId, 5*time.Second)
+	default:
+		b.println("unhandled event: ", evt.EventType)
+	}
+	return nil
+}
+
+// processTask calculates a task, sends it to the aggregator and records the outcome in the checkpoint.
 //
+// ctx is the node context; the task runs on a context detached from it so it can drain on shutdown.
+// height is the block height of the task's driver event.
 // taskId is the unique identifier of the task.
-// Returns an error if there is an issue with the calculation or sending process.
+// delay is how long to wait before the task is calculated.
+// Returns only fatal errors; task failures are logged.
+func (b *bvsNode) processTask(ctx context.Context, height int64, taskId string, delay time.Duration) error {
+	b.taskHeight.Store(height)
+	defer b.taskHeight.Store(0)
+	taskCtx, cancel := drainContext(ctx, drainTimeout)
+	defer cancel()
+	err := sleep(taskCtx, delay)
+	if err == nil {
+		err = b.calcTask(taskCtx, height, taskId)
+	}
+	if errors.Is(err, ErrAlreadySubmitted) {
+		b.printf("Task %s already submitted to aggregator\n", taskId)
+		err = nil
+	}
+	if IsFatal(err) {
+		return err
+	}
+	if err != nil {
+		b.log.Error(fmt.Sprintf("Failed to process task {%s}, due to {%s}", taskId, err))
+	}
+	// transient failures are retried by retryFailed, retrying cannot fix the others
+	done := err == nil || !isRetryable(err)
+	dropped, cpErr := b.checkpoint.Advance(height, taskId, done)
+	if cpErr != nil {
+		b.log.Error(fmt.Sprintf("Failed to save checkpoint, due to {%s}", cpErr))
+	}
+	if done {
+		b.stateBankView.forget(taskId)
+	}
+	for _, id := range dropped {
+		b.log.Error(fmt.Sprintf("Giving up on task {%s}, its event left the replay window", id))
+		b.stateBankView.forget(id)
+	}
+	return nil
+}
+
+// retryFailed processes the tasks that failed with a transient error again, see processTask.
+//
+// ctx is the node context.
+// Returns only fatal errors.
+func (b *bvsNode) retryFailed(ctx context.Context) error {
+	for taskId, height := range b.checkpoint.FailedTasks() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		b.printf("Retrying task %s\n", taskId)
+		if err := b.processTask(ctx, height, taskId, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// drainContext returns a context that is not cancelled with ctx, but at most grace after it.
+func drainContext(ctx context.Context, grace time.Duration) (context.Context, context.CancelFunc) {
+	drainCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(grace, cancel)
+	})
+	return drainCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// startHeight returns the block height the indexers start from.
+//
+// It resumes from the persisted checkpoint if there is one, otherwise starts at the chain head.
+// ctx is the context for the node status query.
+// Returns the height or an error if the chain head cannot be queried.
+func (b *bvsNode) startHeight(ctx context.Context) (int64, error) {
+	var latest int64
+	err := retry(ctx, "query node status", defaultBackoff, func() error {
+		res, err := b.chainIO.QueryNodeStatus(ctx)
+		if err != nil {
+			return err
+		}
+		latest = res.SyncInfo.LatestBlockHeight
+		return nil
+	})
+	if err != nil {
+		return 0, fatal("query node status", err)
+	}
+	return b.checkpoint.StartHeight(latest), nil
+}
+
 // calcTask calculates the task result and sends it to the aggregator.
 //
+// ctx is the context for the calcTask function.
+// height is the block height of the task's driver event.
 // taskId is the unique identifier of the task.
 // Returns an error if there is an issue with the calculation or sending process.
-func (n *Node) calcTask(taskId string) (err error) {
-	stateKey := fmt.Sprintf("taskId.%s", taskId)
-	value, err := n.stateBank.GetWasmUpdateState(stateKey)
+func (b *bvsNode) calcTask(ctx context.Context, height int64, taskId string) (err error) {
+	task, err := strconv.Atoi(taskId)
 	if err != nil {
-		return err
+		return permanent(fmt.Errorf("invalid task id %s: %v", taskId, err))
 	}
 
-	task, err := strconv.Atoi(taskId)
-	if err != nil {
-		fmt.Println("format err:", err)
+	stateKey := fmt.Sprintf("taskId.%s", taskId)
+	var value string
+	err = retry(ctx, "read task assignment", defaultBackoff, func() (err error) {
+		value, err = b.stateBank.GetWasmUpdateState(stateKey)
 		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read task assignment: %v", err)
 	}
-	_, address, err := util.PubKeyToAddress(n.pubKeyStr)
+	var nw *network
+	err = retry(ctx, "read task network", defaultBackoff, func() (err error) {
+		nw, err = b.networkForTask(taskId, height)
+		return err
+	})
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// Check if we're the performer
-	if value == address {
-		fmt.Printf("Selected as performer for task %s\n", taskId)
-		latestBlockNumber, latestBlockHash, err := n.fetchLatestBlockData()
+	if value == b.address {
+		b.printf("Selected as performer for task %s on network %s\n", taskId, nw.name)
+		var latestBlockNumber int64
+		var latestBlockHash string
+		err = retry(ctx, "fetch latest block", defaultBackoff, func() (err error) {
+			latestBlockNumber, latestBlockHash, err = b.fetchLatestBlockData(ctx, nw)
+			return err
+		})
 		if err != nil {
-			panic(err)
+			return fmt.Errorf("failed to fetch latest block: %v", err)
 		}
 
-		fmt.Printf("Performer data - Block Number: %d, Hash: %s\n", latestBlockNumber, latestBlockHash)
+		b.printf("Performer data - Block Number: %d, Hash: %s\n", latestBlockNumber, latestBlockHash)
 
-		result := fmt.Sprintf("%d-%s", latestBlockNumber, latestBlockHash)
-		if err = n.sendAggregator(int64(task), result, "performer"); err != nil {
-			panic(err)
+		result := prober.FormatResult(prober.Block{Height: latestBlockNumber, Hash: latestBlockHash})
+		if err = b.submit(ctx, int64(task), nw, result, payload.RolePerformer); err != nil {
+			return err
 		}
+		b.metrics.TasksPerformed.Inc()
 		return nil
 	}
 
-	// We're an attester, try multiple times to get performer's data
-	fmt.Printf("Acting as attester for task %s, waiting for performer data...\n", taskId)
+	// We're an attester, wait for the performer's data
+	b.printf("Acting as attester for task %s, waiting for performer data...\n", taskId)
 
+	performerData, err := b.waitPerformerData(ctx, taskId)
+	if err != nil {
+		return err
+	}
+
+	var isValid bool
+	err = retry(ctx, "validate performer data", defaultBackoff, func() (err error) {
+		isValid, err = b.validatePerformerData(ctx, nw, performerData, value)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("validation failed: %v", err)
+	}
+
+	result := "true"
+	if !isValid {
+		result = "false"
+		b.metrics.ValidationFailures.Inc()
+	}
+
+	if err = b.submit(ctx, int64(task), nw, result, payload.RoleAttester); err != nil {
+		return err
+	}
+	b.metrics.TasksAttested.Inc()
+
+	b.printf("Successfully sent attestation for task %s\n", taskId)
+	return nil
+}
+
+// getPerformerData polls the aggregator for the performer's submission of a task.
+//
+// It is the fallback for aggregators that do not offer the task stream.
+// ctx is the context for the requests.
+// taskId is the unique identifier of the task.
+// Returns the performer's data, or an error if it did not show up in time.
+func (b *bvsNode) getPerformerData(ctx context.Context, taskId string) (PerformerData, error) {
 	// Retry configuration
 	maxRetries := 5               // Try 5 times
 	retryDelay := 3 * time.Second // Wait 3 seconds between tries
 
 	var performerData PerformerData
-	var gotData bool
-
 	for i := 0; i < maxRetries; i++ {
 		if i > 0 {
-			fmt.Printf("Retry %d/%d for task %s\n", i+1, maxRetries, taskId)
+			if err := sleep(ctx, retryDelay); err != nil {
+				return performerData, err
+			}
+			b.printf("Retry %d/%d for task %s\n", i+1, maxRetries, taskId)
 		}
 
 		// Try to get performer's data
-		url := fmt.Sprintf("%s/task/%s", core.C.Aggregator.Url, taskId)
-		resp, err := http.Get(url)
+		url := fmt.Sprintf("%s/task/%s", b.cfg.Aggregator, taskId)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
-			fmt.Printf("Failed to get performer data: %v, retrying...\n", err)
-			time.Sleep(retryDelay)
+			return performerData, permanent(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			b.metrics.AggregatorErrors.Inc()
+			b.printf("Failed to get performer data: %v, retrying...\n", err)
 			continue
 		}
 
 		if resp.StatusCode == http.StatusNotFound {
 			resp.Body.Close()
-			fmt.Printf("No performer data yet for task %s, retrying...\n", taskId)
-			time.Sleep(retryDelay)
+			b.printf("No performer data yet for task %s, retrying...\n", taskId)
 			continue
 		}
 
-		if err := json.NewDecoder(resp.Body).Decode(&performerData); err != nil {
-			resp.Body.Close()
-			fmt.Printf("Failed to decode performer data: %v, retrying...\n", err)
-			time.Sleep(retryDelay)
+		err = json.NewDecoder(resp.Body).Decode(&performerData)
+		resp.Body.Close()
+		if err != nil {
+			b.printf("Failed to decode performer data: %v, retrying...\n", err)
 			continue
 		}
-		resp.Body.Close()
 
-		gotData = true
-		fmt.Printf("Got performer data for task %s: %s\n", taskId, performerData.Result)
-		break
+		b.printf("Got performer data for task %s: %s\n", taskId, performerData.Result)
+		return performerData, nil
 	}
 
-	if !gotData {
-		return fmt.Errorf("failed to get performer data after %d retries", maxRetries)
-	}
-
-	isValid, err := n.validatePerformerData(performerData, value)
-	if err != nil {
-		return fmt.Errorf("validation failed: %v", err)
-	}
-
-	result := "true"
-	if !isValid {
-		result = "false"
-	}
-
-	if err = n.sendAggregator(int64(task), result, "attester"); err != nil {
-		panic(err)
-	}
-
-	fmt.Printf("Successfully sent attestation for task %s\n", taskId)
-	return nil
+	return performerData, fmt.Errorf("failed to get performer data after %d retries", maxRetries)
 }
 
-// fetchLatestBlockData retrieves the latest block number and its hash from the configured Babylon RPC endpoint.
+// fetchLatestBlockData retrieves the latest block number and its hash from the network's upstream RPC.
 //
+// ctx is the context for the request.
+// nw is the network the task targets.
 // Returns the block number as an int64 and the block hash as a string.
 // If there is an error during the retrieval, an error will be returned.
-func (n *Node) fetchLatestBlockData() (int64, string, error) {
-	// First, get the status to find the latest block height
-	resp, err := http.Get(fmt.Sprintf("%s/status", core.C.Rpc.Endpoint))
-	if err != nil {
-		return 0, "", fmt.Errorf("failed to get status: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var statusResp core.StatusResponse
-	if err := json.NewDecoder(resp.Body).Decode(&statusResp); err != nil {
-		return 0, "", fmt.Errorf("failed to decode status response: %v", err)
-	}
-
-	// Convert block height from string to int64
-	latestHeight, err := strconv.ParseInt(statusResp.Result.SyncInfo.LatestBlockHeight, 10, 64)
+func (b *bvsNode) fetchLatestBlockData(ctx context.Context, nw *network) (int64, string, error) {
+	block, err := nw.prober.LatestBlock(ctx)
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to parse block height: %v", err)
+		return 0, "", err
 	}
 
-	// Now fetch the block details using the height
-	blockResp, err := http.Get(fmt.Sprintf("%s/block?height=%d", core.C.Rpc.Endpoint, latestHeight))
-	if err != nil {
-		return 0, "", fmt.Errorf("failed to get block: %v", err)
-	}
-	defer blockResp.Body.Close()
-
-	var block core.BlockResponse
-	if err := json.NewDecoder(blockResp.Body).Decode(&block); err != nil {
-		return 0, "", fmt.Errorf("failed to decode block response: %v", err)
-	}
-
-	return latestHeight, block.Result.BlockID.Hash, nil
+	return block.Height, block.Hash, nil
 }
 
-func (n *Node) validatePerformerData(performerData PerformerData, expectedAddress string) (bool, error) {
+// validatePerformerData checks the performer's block data against the network's upstream RPC.
+//
+// ctx is the context for the request.
+// nw is the network the task targets.
+// performerData is the data the performer submitted to the aggregator.
+// expectedAddress is the performer address assigned to the task.
+// Returns whether the data is valid, or an error if it could not be checked.
+func (b *bvsNode) validatePerformerData(ctx context.Context, nw *network, performerData PerformerData, expectedAddress string) (bool, error) {
 	// Parse performer's data
-	parts := strings.Split(performerData.Result, "-")
-	if len(parts) != 2 {
-		return false, fmt.Errorf("invalid performer data format")
-	}
-
-	performerBlockNumber, err := strconv.ParseInt(parts[0], 10, 64)
+	claimed, err := prober.ParseResult(performerData.Result)
 	if err != nil {
-		return false, fmt.Errorf("failed to parse block number: %v", err)
+		return false, err
 	}
-	performerBlockHash := parts[1]
 
-	// Get current block data for verification
-	currentBlockNumber, _, err := n.fetchLatestBlockData()
+	// Verify block exists, hash matches and block is recent
+	isBlockValid, err := prober.Verify(ctx, nw.verifier, claimed, nw.maxLag)
 	if err != nil {
-		return false, fmt.Errorf("failed to fetch current block data: %v", err)
+		return false, err
 	}
-
-	// Verify block exists and hash matches
-	resp, err := http.Get(fmt.Sprintf("%s/block?height=%d", core.C.Rpc.Endpoint, performerBlockNumber))
-	if err != nil {
-		return false, fmt.Errorf("failed to fetch block data: %v", err)
-	}
-	defer resp.Body.Close()
-
-	var blockResponse core.BlockResponse
-	if err := json.NewDecoder(resp.Body).Decode(&blockResponse); err != nil {
-		return false, fmt.Errorf("failed to decode block data: %v", err)
-	}
-
-	// Get the hash from the block response
-	actualBlockHash := blockResponse.Result.BlockID.Hash
-
-	isBlockValid := actualBlockHash == performerBlockHash
-	isBlockRecent := currentBlockNumber-performerBlockNumber <= 10 // @reminder You might want to adjust this threshold
 	isCorrectPerformer := performerData.Address == expectedAddress
 
-	return isBlockValid && isBlockRecent && isCorrectPerformer, nil
+	return isBlockValid && isCorrectPerformer, nil
+}
+
+// submit sends the task result to the aggregator, retrying transient failures. Every attempt
+// is signed with a fresh nonce.
+//
+// ctx is the context for the requests.
+// taskId is the unique identifier of the task.
+// nw is the network the task targets.
+// result is either block data (for performer) or validation result (for attester)
+// role is either "performer" or "attester"
+// Returns an error if the result could not be delivered.
+func (b *bvsNode) submit(ctx context.Context, taskId int64, nw *network, result string, role string) error {
+	return retry(ctx, "send to aggregator", defaultBackoff, func() error {
+		err := b.sendAggregator(ctx, taskId, nw.name, result, role)
+		if err != nil && !errors.Is(err, ErrAlreadySubmitted) && !IsFatal(err) {
+			b.metrics.AggregatorErrors.Inc()
+		}
+		return err
+	})
 }
 
 // sendAggregator sends the task result to the aggregator.
 //
+// ctx is the context for the request.
 // taskId is the unique identifier of the task.
+// networkName is the name of the network the task targets.
 // result is either block data (for performer) or validation result (for attester)
 // role is either "performer" or "attester"
 // Returns an error if there is an issue with the sending process.
-func (n *Node) sendAggregator(taskId int64, result string, role string) (err error) {
-	nowTs := time.Now().Unix()
-
-	// Create message payload based on role
-	msgPayload := fmt.Sprintf("%s-%d-%d-%s", core.C.Chain.BvsHash, nowTs, taskId, result)
-	core.L.Info(fmt.Sprintf("msgPayload: %s\n", msgPayload))
-
-	signature, err := n.chainIO.GetSigner().Sign([]byte(msgPayload))
-	if err != nil {
-		return fmt.Errorf("failed to sign payload: %v", err)
-	}
-
-	payload := Payload{
-		TaskId:    taskId,
+func (b *bvsNode) sendAggregator(ctx context.Context, taskId int64, networkName string, result string, role string) (err error) {
+	submission := payload.Submission{
+		TaskId:    uint64(taskId),
 		Result:    result, // For performer: "blockNum-hash", for attester: "true"/"false"
-		Timestamp: nowTs,
-		Signature: signature,
-		PubKey:    n.pubKeyStr,
+		Timestamp: time.Now().Unix(),
+		Nonce:     b.nextNonce(),
+		PubKey:    b.pubKeyStr,
 		Role:      role,
+		Network:   networkName,
+	}
+	if err := submission.Sign(b.chainIO.GetSigner(), core.C.Chain.Id, b.cfg.BvsHash); err != nil {
+		return fatal("sign payload", err)
 	}
 
-	fmt.Printf("Sending to aggregator - Role: %s, TaskId: %d, Result: %s\n", role, taskId, result)
+	b.printf("Sending to aggregator - Role: %s, TaskId: %d, Result: %s\n", role, taskId, result)
 
-	jsonData, err := json.Marshal(payload)
+	if b.aggregator != nil {
+		if err := b.submitGrpc(ctx, submission); err != nil {
+			return err
+		}
+		b.printf("Successfully sent %s data to aggregator for task %d\n", role, taskId)
+		return nil
+	}
+
+	jsonData, err := json.Marshal(submission)
 	if err != nil {
-		fmt.Printf("Error marshaling JSON: %s\n", err)
-		return fmt.Errorf("failed to marshal payload: %v", err)
+		b.printf("Error marshaling JSON: %s\n", err)
+		return permanent(fmt.Errorf("failed to marshal payload: %v", err))
 	}
 
-	resp, err := http.Post(core.C.Aggregator.Url, "application/json", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.cfg.Aggregator, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		fmt.Printf("Error sending to aggregator: %s\n", err)
+		b.printf("Error sending to aggregator: %s\n", err)
 		return fmt.Errorf("failed to send to aggregator: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		body, _ := rio.ReadAll(resp.Body)
-		fmt.Printf("Error from aggregator: %s\n", string(body))
-		return fmt.Errorf("aggregator returned non-200 status: %d, body: %s", resp.StatusCode, body)
+		b.printf("Error from aggregator: %s\n", string(body))
+		if isAlreadySubmitted(body) {
+			return ErrAlreadySubmitted
+		}
+		if resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("%w: %s", errNonceReused, body)
+		}
+		err := fmt.Errorf("aggregator returned non-200 status: %d, body: %s", resp.StatusCode, body)
+		// 429 is the aggregator's rate limit, worth retrying after the backoff
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return permanent(err)
+		}
+		return err
 	}
 
-	fmt.Printf("Successfully sent %s data to aggregator for task %d\n", role, taskId)
+	b.printf("Successfully sent %s data to aggregator for task %d\n", role, taskId)
 	return nil
 }
+
+// nextNonce returns a nonce greater than any this node used before.
+//
+// Nonces are based on the wall clock so they keep increasing across restarts; the aggregator
+// rejects any nonce that is not greater than the operator's last one. Concurrent submissions
+// can reach it out of order, so submit retries such a rejection with a fresh nonce.
+func (n *Node) nextNonce() uint64 {
+	for {
+		last := n.lastNonce.Load()
+		next := uint64(time.Now().UnixNano())
+		if next <= last {
+			next = last + 1
+		}
+		if n.lastNonce.CompareAndSwap(last, next) {
+			return next
+		}
+	}
+}
+
+// isAlreadySubmitted reports whether an aggregator error body rejects a duplicate submission.
+func isAlreadySubmitted(body []byte) bool {
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return false
+	}
+	return strings.HasSuffix(errResp.Error, "already submitted") || errResp.Error == "task already finished"
+}
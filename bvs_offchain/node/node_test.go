@@ -0,0 +1,87 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
+)
+
+func testBvs(names ...string) []*bvsNode {
+	bvs := make([]*bvsNode, len(names))
+	for i, name := range names {
+		bvs[i] = &bvsNode{cfg: core.Bvs{Name: name}}
+	}
+	return bvs
+}
+
+func TestRunAllShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var running, stopped atomic.Int32
+	done := make(chan error)
+	go func() {
+		done <- runAll(ctx, testBvs("a", "b", "c"), func(b *bvsNode, ctx context.Context) error {
+			running.Add(1)
+			<-ctx.Done()
+			stopped.Add(1)
+			return ctx.Err()
+		})
+	}()
+
+	require.Eventually(t, func() bool { return running.Load() == 3 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int32(3), stopped.Load(), "returns once every BVS stopped")
+	case <-time.After(time.Second):
+		t.Fatal("runAll did not return after shutdown")
+	}
+}
+
+func TestRunAllFatalStopsOthers(t *testing.T) {
+	cause := fatal("sign", errors.New("keyring locked"))
+	var stopped atomic.Int32
+	err := runAll(context.Background(), testBvs("a", "b", "c"), func(b *bvsNode, ctx context.Context) error {
+		if b.cfg.Name == "b" {
+			return cause
+		}
+		<-ctx.Done()
+		stopped.Add(1)
+		return ctx.Err()
+	})
+	assert.ErrorIs(t, err, cause)
+	assert.True(t, IsFatal(err))
+	assert.Contains(t, err.Error(), "bvs b:")
+	assert.Equal(t, int32(2), stopped.Load(), "the other BVS are stopped")
+}
+
+func TestDrainContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	drainCtx, stop := drainContext(ctx, 50*time.Millisecond)
+	defer stop()
+
+	cancel()
+	assert.NoError(t, drainCtx.Err(), "the task keeps running after shutdown was requested")
+	select {
+	case <-drainCtx.Done():
+		assert.ErrorIs(t, drainCtx.Err(), context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("drain context not cancelled after the grace period")
+	}
+}
+
+func TestDrainContextStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	drainCtx, stop := drainContext(ctx, time.Hour)
+
+	stop()
+	assert.ErrorIs(t, drainCtx.Err(), context.Canceled, "stop cancels right away")
+}
@@ -0,0 +1,62 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
+)
+
+// Backoff configures how often and how fast an operation is retried.
+type Backoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+var defaultBackoff = Backoff{
+	Attempts: 5,
+	Initial:  time.Second,
+	Max:      30 * time.Second,
+}
+
+// retry calls fn until it succeeds, returns a non-retryable error, the attempts are used up or ctx is done.
+//
+// ctx is the context bounding the retries.
+// op names the operation in logs.
+// b is the backoff policy; the delay doubles after every failed attempt up to b.Max.
+// Returns nil on success, otherwise the last error from fn or the context error.
+func retry(ctx context.Context, op string, b Backoff, fn func() error) error {
+	delay := b.Initial
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isRetryable(err) || attempt >= b.Attempts {
+			return err
+		}
+
+		core.L.Info(fmt.Sprintf("%s failed (attempt %d/%d), retrying in %s, due to {%s}", op, attempt, b.Attempts, delay, err))
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+		delay *= 2
+		if delay > b.Max {
+			delay = b.Max
+		}
+	}
+}
+
+// sleep pauses for d or until ctx is done.
+//
+// Returns the context error if ctx finished first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testBackoff = Backoff{Attempts: 4, Initial: time.Millisecond, Max: 2 * time.Millisecond}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	calls := 0
+	err := retry(context.Background(), "test", testBackoff, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetryUsesUpAttempts(t *testing.T) {
+	calls := 0
+	cause := errors.New("unavailable")
+	err := retry(context.Background(), "test", testBackoff, func() error {
+		calls++
+		return cause
+	})
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, testBackoff.Attempts, calls)
+}
+
+func TestRetryStopsOnNonRetryable(t *testing.T) {
+	for name, cause := range map[string]error{
+		"permanent":         permanent(errors.New("rejected")),
+		"fatal":             fatal("sign", errors.New("keyring locked")),
+		"already submitted": ErrAlreadySubmitted,
+	} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			err := retry(context.Background(), "test", testBackoff, func() error {
+				calls++
+				return cause
+			})
+			assert.ErrorIs(t, err, cause)
+			assert.Equal(t, 1, calls)
+		})
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	b := Backoff{Attempts: 5, Initial: 10 * time.Millisecond, Max: 20 * time.Millisecond}
+	var calls []time.Time
+	_ = retry(context.Background(), "test", b, func() error {
+		calls = append(calls, time.Now())
+		return errors.New("unavailable")
+	})
+	// the delay doubles from 10ms and is capped at 20ms
+	for i, min := range []time.Duration{10, 20, 20, 20} {
+		assert.GreaterOrEqual(t, calls[i+1].Sub(calls[i]), min*time.Millisecond, "delay before attempt %d", i+2)
+	}
+}
+
+func TestRetryStopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := retry(ctx, "test", Backoff{Attempts: 5, Initial: time.Hour, Max: time.Hour}, func() error {
+		calls++
+		cancel()
+		return errors.New("unavailable")
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, calls)
+}
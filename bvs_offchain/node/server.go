@@ -0,0 +1,146 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/satlayer/satlayer-api/chainio/io"
+
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
+)
+
+// serveMetrics serves /metrics, /healthz and /readyz on core.C.Metrics.Host until ctx is done.
+//
+// ctx is the node context.
+// Returns an error if the server fails for any reason other than shutdown.
+func (n *Node) serveMetrics(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(n.registry, promhttp.HandlerOpts{}))
+	mux.HandleFunc("/healthz", n.handleHealthz)
+	mux.HandleFunc("/readyz", n.handleReadyz)
+
+	srv := &http.Server{Addr: core.C.Metrics.Host, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	core.L.Info(fmt.Sprintf("Start metrics server at {%s}", core.C.Metrics.Host))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// indexerPollInterval is how often the node checks whether it caught up with its indexers.
+const indexerPollInterval = 2 * time.Second
+
+// keyringProbeInterval is how often the keyring is probed for the health checks.
+const keyringProbeInterval = time.Minute
+
+// handleHealthz reports whether the node is alive, i.e. its keyring could sign at the latest probe.
+func (n *Node) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"keyring": n.keyringStatus()}
+	writeChecks(w, checks)
+}
+
+// handleReadyz reports whether the node is ready to process tasks: keyring available and
+// caught up with both indexers of every BVS.
+func (n *Node) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"keyring": n.keyringStatus()}
+	for _, b := range n.bvs {
+		checks[b.cfg.Name+".stateBankIndexer"] = b.stateBankIdx.status()
+		checks[b.cfg.Name+".driverIndexer"] = b.driverIdx.status()
+	}
+	writeChecks(w, checks)
+}
+
+// checkKeyring signs a probe message to check that the keyring is available.
+//
+// It runs at startup and every keyringProbeInterval, never per health request, so the health
+// checks cannot make the node use its key, which may live in a remote signer.
+func checkKeyring(chainIO io.ChainIO) string {
+	if _, err := chainIO.GetSigner().Sign([]byte("healthz")); err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
+
+// probeKeyring caches the result of checkKeyring every interval until ctx is done.
+func (n *Node) probeKeyring(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n.keyring.Store(checkKeyring(n.chainIO))
+	}
+}
+
+// keyringStatus returns the result of the latest keyring probe.
+func (n *Node) keyringStatus() string {
+	status, _ := n.keyring.Load().(string)
+	return status
+}
+
+// indexerState is whether an indexer runs and the node caught up with its events, for the
+// readiness check.
+type indexerState struct {
+	started  atomic.Bool
+	upToDate atomic.Bool
+}
+
+// watch records whether caughtUp reports the node caught up with an indexer every interval,
+// until ctx is done.
+//
+// caughtUp may only read state the node synchronises itself. The indexer's IsUpToDate is set
+// from its own goroutine without synchronisation, so it is never read.
+func (s *indexerState) watch(ctx context.Context, caughtUp func() bool, interval time.Duration) {
+	s.started.Store(true)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.upToDate.Store(caughtUp())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// status describes the indexer for the readiness check.
+func (s *indexerState) status() string {
+	switch {
+	case !s.started.Load():
+		return "not started"
+	case !s.upToDate.Load():
+		return "syncing"
+	default:
+		return "ok"
+	}
+}
+
+// writeChecks writes the checks as JSON with 200 if all of them are "ok", 503 otherwise.
+func writeChecks(w http.ResponseWriter, checks map[string]string) {
+	status := http.StatusOK
+	for _, result := range checks {
+		if result != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(checks)
+}
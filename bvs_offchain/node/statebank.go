@@ -0,0 +1,104 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/satlayer/satlayer-api/chainio/indexer"
+)
+
+// errStateNotIndexed is returned while the StateBank events are not handled up to the height of
+// a task's driver event, so a key of the task may not be indexed yet.
+var errStateNotIndexed = errors.New("state bank not indexed up to the task yet")
+
+// stateBankTaskNetwork is the prefix of the StateBank keys that name the network of a task.
+const stateBankTaskNetwork = "taskNetwork."
+
+// stateBankView is what the node saw of the StateBank events it hands to the StateBank client:
+// the height of the latest of them and the task networks they set.
+//
+// The squaring contract writes a task's assignment to the StateBank in the transaction that
+// emits its driver event, so the StateBank events always reach the height of a task.
+type stateBankView struct {
+	mu       sync.Mutex
+	height   int64
+	networks map[string]string
+}
+
+func newStateBankView() *stateBankView {
+	return &stateBankView{networks: make(map[string]string)}
+}
+
+// forward hands the events of in to out in order and records each one out took, until in is
+// closed or ctx is done.
+//
+// out is closed when forward returns.
+func (v *stateBankView) forward(ctx context.Context, in <-chan *indexer.Event, out chan<- *indexer.Event) {
+	defer close(out)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case evt, ok := <-in:
+			if !ok {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- evt:
+			}
+			v.record(evt)
+		}
+	}
+}
+
+// record notes the height of a StateBank event and the task network it sets, if any.
+func (v *stateBankView) record(evt *indexer.Event) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if evt.BlockHeight > v.height {
+		v.height = evt.BlockHeight
+	}
+	if taskId, ok := strings.CutPrefix(evt.AttrMap["key"], stateBankTaskNetwork); ok {
+		v.networks[taskId] = evt.AttrMap["value"]
+	}
+}
+
+// Height returns the block height of the latest StateBank event handed to the StateBank client.
+func (v *stateBankView) Height() int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.height
+}
+
+// network returns the network the StateBank key "taskNetwork.<id>" of a task names.
+//
+// taskId is the unique identifier of the task.
+// height is the block height of the task's driver event.
+// Returns the network, "" if the key was not set up to height, or errStateNotIndexed if the
+// events are not handled up to height yet.
+func (v *stateBankView) network(taskId string, height int64) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if name, ok := v.networks[taskId]; ok {
+		return name, nil
+	}
+	if v.height < height {
+		return "", errStateNotIndexed
+	}
+	return "", nil
+}
+
+// forget drops the network of a task that will not be processed again.
+func (v *stateBankView) forget(taskId string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.networks, taskId)
+}
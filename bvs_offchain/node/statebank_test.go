@@ -0,0 +1,42 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/satlayer/satlayer-api/chainio/indexer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateBankViewNetwork(t *testing.T) {
+	v := newStateBankView()
+	in := make(chan *indexer.Event, 2)
+	out := make(chan *indexer.Event)
+	go v.forward(context.Background(), in, out)
+
+	// a missing key is only absent once the events reach the task's height
+	_, err := v.network("7", 10)
+	assert.ErrorIs(t, err, errStateNotIndexed)
+
+	in <- &indexer.Event{BlockHeight: 9, AttrMap: map[string]string{"key": "taskNetwork.7", "value": "bitcoin"}}
+	in <- &indexer.Event{BlockHeight: 10, AttrMap: map[string]string{"key": "taskId.8", "value": "operator"}}
+	close(in)
+	for range out {
+	}
+
+	name, err := v.network("7", 10)
+	require.NoError(t, err)
+	assert.Equal(t, "bitcoin", name)
+	name, err = v.network("8", 10)
+	require.NoError(t, err)
+	assert.Empty(t, name, "a task without the key targets the default network")
+	_, err = v.network("9", 11)
+	assert.ErrorIs(t, err, errStateNotIndexed)
+	assert.True(t, isRetryable(errStateNotIndexed))
+
+	v.forget("7")
+	name, err = v.network("7", 10)
+	require.NoError(t, err)
+	assert.Empty(t, name)
+}
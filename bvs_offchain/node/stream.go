@@ -0,0 +1,96 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// errStreamUnavailable is returned when the aggregator does not offer the task stream.
+var errStreamUnavailable = errors.New("task stream unavailable")
+
+// waitPerformerData waits for the performer's submission of a task.
+//
+// It subscribes to the aggregator's Server-Sent Events stream of the task, or its gRPC
+// stream when configured, and only falls back to polling when the stream is unavailable.
+// ctx is the context for the requests.
+// taskId is the unique identifier of the task.
+// Returns the performer's data, or an error if it did not show up in time.
+func (b *bvsNode) waitPerformerData(ctx context.Context, taskId string) (PerformerData, error) {
+	if b.aggregator != nil {
+		performerData, err := b.streamPerformerDataGrpc(ctx, taskId)
+		if errors.Is(err, errStreamUnavailable) {
+			b.log.Info(fmt.Sprintf("Task stream unavailable, polling for performer data, due to {%s}", err))
+			return b.getPerformerDataGrpc(ctx, taskId)
+		}
+		return performerData, err
+	}
+	performerData, err := b.streamPerformerData(ctx, taskId)
+	if errors.Is(err, errStreamUnavailable) {
+		b.log.Info(fmt.Sprintf("Task stream unavailable, polling for performer data, due to {%s}", err))
+		return b.getPerformerData(ctx, taskId)
+	}
+	return performerData, err
+}
+
+// streamPerformerData reads the performer's submission from the aggregator's task stream.
+//
+// ctx is the context for the request.
+// taskId is the unique identifier of the task.
+// Returns the performer's data, errStreamUnavailable if the stream could not be opened,
+// or an error if the stream ended without the performer's data.
+func (b *bvsNode) streamPerformerData(ctx context.Context, taskId string) (PerformerData, error) {
+	var performerData PerformerData
+
+	url := fmt.Sprintf("%s/task/%s/stream", b.cfg.Aggregator, taskId)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return performerData, permanent(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return performerData, fmt.Errorf("%w: %v", errStreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return performerData, fmt.Errorf("%w: status %d", errStreamUnavailable, resp.StatusCode)
+	}
+
+	b.printf("Subscribed to task %s, waiting for performer data...\n", taskId)
+	var event, data string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// blank line dispatches the event
+			switch event {
+			case "performer":
+				if err := json.Unmarshal([]byte(data), &performerData); err != nil {
+					return performerData, fmt.Errorf("failed to decode performer data: %v", err)
+				}
+				b.printf("Got performer data for task %s: %s\n", taskId, performerData.Result)
+				return performerData, nil
+			case "timeout":
+				return performerData, fmt.Errorf("no performer data for task %s before the stream timed out", taskId)
+			}
+			event, data = "", ""
+		case strings.HasPrefix(line, ":"):
+			// comment, used as keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return performerData, fmt.Errorf("task stream interrupted: %v", err)
+	}
+	return performerData, fmt.Errorf("task stream closed without performer data")
+}
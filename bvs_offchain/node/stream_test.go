@@ -0,0 +1,122 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/satlayer/satlayer-api/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/satlayer/hello-world-bvs/bvs_offchain/core"
+)
+
+// testAggregator returns a node of a BVS whose aggregator serves the task stream and the task
+// data of task 1 with the given handlers.
+func testAggregator(t *testing.T, stream http.HandlerFunc, task http.HandlerFunc) *bvsNode {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/aggregator/task/1/stream", stream)
+	mux.HandleFunc("/api/aggregator/task/1", task)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return &bvsNode{
+		cfg:     core.Bvs{Name: "test", Aggregator: server.URL + "/api/aggregator"},
+		metrics: NewMetrics(prometheus.NewRegistry()).forBvs("test"),
+		log:     logger.NewELKLogger("test"),
+	}
+}
+
+// sse writes events the way the aggregator's StreamTaskData does.
+func sse(events ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": keep-alive\n\n")
+		for _, event := range events {
+			fmt.Fprint(w, event)
+		}
+	}
+}
+
+func performerJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, `{"result":"100-ABCD","address":"performer"}`)
+}
+
+func unexpected(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s", r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func TestStreamPerformerData(t *testing.T) {
+	b := testAggregator(t, sse("event:performer\ndata:{\"result\":\"100-ABCD\",\"address\":\"performer\"}\n\n"), unexpected(t))
+
+	data, err := b.waitPerformerData(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, PerformerData{Result: "100-ABCD", Address: "performer"}, data)
+}
+
+func TestStreamTimeout(t *testing.T) {
+	b := testAggregator(t, sse("event:timeout\ndata:{\"error\":\"performer data not found\"}\n\n"), unexpected(t))
+
+	_, err := b.waitPerformerData(context.Background(), "1")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, errStreamUnavailable, "a timed out stream does not fall back to polling")
+	assert.True(t, isRetryable(err))
+}
+
+func TestStreamClosedWithoutPerformer(t *testing.T) {
+	b := testAggregator(t, sse(), unexpected(t))
+
+	_, err := b.streamPerformerData(context.Background(), "1")
+	assert.EqualError(t, err, "task stream closed without performer data")
+}
+
+func TestStreamFallbackToPolling(t *testing.T) {
+	for name, status := range map[string]int{
+		// an aggregator without the stream route
+		"not found": http.StatusNotFound,
+		// the aggregator could not subscribe to the task
+		"internal error": http.StatusInternalServerError,
+	} {
+		t.Run(name, func(t *testing.T) {
+			var polled atomic.Int32
+			b := testAggregator(t,
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(status)
+					fmt.Fprint(w, `{"error":"failed to subscribe to task"}`)
+				},
+				func(w http.ResponseWriter, r *http.Request) {
+					polled.Add(1)
+					performerJSON(w, r)
+				})
+
+			data, err := b.waitPerformerData(context.Background(), "1")
+			require.NoError(t, err)
+			assert.Equal(t, "100-ABCD", data.Result)
+			assert.Equal(t, int32(1), polled.Load())
+		})
+	}
+}
+
+func TestStreamFallbackWhenUnreachable(t *testing.T) {
+	b := testAggregator(t, unexpected(t), unexpected(t))
+	b.cfg.Aggregator = "http://127.0.0.1:1/api/aggregator"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := b.streamPerformerData(context.Background(), "1")
+	assert.ErrorIs(t, err, errStreamUnavailable)
+	// polling gives up once ctx is done
+	_, err = b.waitPerformerData(ctx, "1")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,78 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StatusResponse struct {
+	JsonRPC string `json:"jsonrpc"`
+	Result  struct {
+		SyncInfo struct {
+			LatestBlockHeight string    `json:"latest_block_height"`
+			LatestBlockHash   string    `json:"latest_block_hash"`
+			LatestBlockTime   time.Time `json:"latest_block_time"`
+		} `json:"sync_info"`
+	} `json:"result"`
+}
+
+type BlockResponse struct {
+	JsonRPC string `json:"jsonrpc"`
+	Result  struct {
+		BlockID struct {
+			Hash string `json:"hash"`
+		} `json:"block_id"`
+		Block struct {
+			Header struct {
+				Height  string    `json:"height"`
+				Time    time.Time `json:"time"`
+				ChainID string    `json:"chain_id"`
+			} `json:"header"`
+		} `json:"block"`
+	} `json:"result"`
+}
+
+type cometBFTProber struct {
+	endpoint string
+}
+
+// NewCometBFTProber creates a Prober speaking CometBFT RPC (/status, /block?height=).
+//
+// endpoint is the CometBFT RPC url.
+// Returns the prober.
+func NewCometBFTProber(endpoint string) Prober {
+	return &cometBFTProber{endpoint: strings.TrimRight(endpoint, "/")}
+}
+
+func (p *cometBFTProber) Kind() string {
+	return KindCometBFT
+}
+
+func (p *cometBFTProber) LatestBlock(ctx context.Context) (Block, error) {
+	var statusResp StatusResponse
+	if err := getJSON(ctx, fmt.Sprintf("%s/status", p.endpoint), &statusResp); err != nil {
+		return Block{}, fmt.Errorf("failed to get status: %v", err)
+	}
+
+	latestHeight, err := strconv.ParseInt(statusResp.Result.SyncInfo.LatestBlockHeight, 10, 64)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to parse block height: %v", err)
+	}
+
+	return p.BlockAt(ctx, latestHeight)
+}
+
+func (p *cometBFTProber) BlockAt(ctx context.Context, height int64) (Block, error) {
+	var blockResp BlockResponse
+	if err := getJSON(ctx, fmt.Sprintf("%s/block?height=%d", p.endpoint, height), &blockResp); err != nil {
+		return Block{}, fmt.Errorf("failed to get block: %v", err)
+	}
+	if blockResp.Result.BlockID.Hash == "" {
+		return Block{}, fmt.Errorf("block %d not found", height)
+	}
+
+	return Block{Height: height, Hash: blockResp.Result.BlockID.Hash}, nil
+}
@@ -0,0 +1,58 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type evmBlock struct {
+	Number string `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+type evmProber struct {
+	endpoint string
+}
+
+// NewEVMProber creates a Prober speaking Ethereum JSON-RPC (eth_blockNumber, eth_getBlockByNumber).
+//
+// endpoint is the JSON-RPC url.
+// Returns the prober.
+func NewEVMProber(endpoint string) Prober {
+	return &evmProber{endpoint: endpoint}
+}
+
+func (p *evmProber) Kind() string {
+	return KindEVM
+}
+
+func (p *evmProber) LatestBlock(ctx context.Context) (Block, error) {
+	var blockNumber string
+	if err := callJSONRPC(ctx, p.endpoint, "2.0", "eth_blockNumber", nil, &blockNumber); err != nil {
+		return Block{}, fmt.Errorf("failed to get block number: %v", err)
+	}
+
+	latestHeight, err := parseHexInt(blockNumber)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to parse block number: %v", err)
+	}
+
+	return p.BlockAt(ctx, latestHeight)
+}
+
+func (p *evmProber) BlockAt(ctx context.Context, height int64) (Block, error) {
+	var block evmBlock
+	params := []interface{}{fmt.Sprintf("0x%x", height), false}
+	if err := callJSONRPC(ctx, p.endpoint, "2.0", "eth_getBlockByNumber", params, &block); err != nil {
+		return Block{}, fmt.Errorf("failed to get block: %v", err)
+	}
+
+	return Block{Height: height, Hash: strings.ToLower(block.Hash)}, nil
+}
+
+// parseHexInt parses a 0x-prefixed quantity as returned by Ethereum JSON-RPC.
+func parseHexInt(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(s, "0x"), 16, 64)
+}
@@ -0,0 +1,133 @@
+package prober
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/cometbft/cometbft/light"
+	dbs "github.com/cometbft/cometbft/light/store/db"
+	"github.com/cometbft/cometbft/types"
+)
+
+// LightConfig is the root of trust of a CometBFT light client.
+type LightConfig struct {
+	ChainId string
+	// Primary is the RPC the light client reads headers from.
+	Primary string
+	// Witnesses are cross-checked against the primary to detect forks; at least one is required.
+	Witnesses []string
+	// TrustedHeight and TrustedHash identify a header obtained from a trusted source.
+	TrustedHeight int64
+	TrustedHash   string
+	// TrustingPeriod must be well below the chain's unbonding period.
+	TrustingPeriod time.Duration
+	// StoreDir is the directory the verified headers are kept in, so a restarted client resumes
+	// from the latest of them. Empty keeps them in memory.
+	StoreDir string
+}
+
+// Validate checks the configuration without contacting any upstream.
+func (cfg LightConfig) Validate() error {
+	if cfg.ChainId == "" {
+		return fmt.Errorf("light client needs a chain id")
+	}
+	if len(cfg.Witnesses) == 0 {
+		return fmt.Errorf("light client needs at least one witness")
+	}
+	hash, err := hex.DecodeString(cfg.TrustedHash)
+	if err != nil {
+		return fmt.Errorf("invalid trusted hash: %v", err)
+	}
+	opts := light.TrustOptions{Period: cfg.TrustingPeriod, Height: cfg.TrustedHeight, Hash: hash}
+	if err := opts.ValidateBasic(); err != nil {
+		return fmt.Errorf("invalid trust options: %v", err)
+	}
+	return nil
+}
+
+// LightProber is a Prober backed by a CometBFT light client.
+//
+// Every block it reports is verified from the trusted header through validator-set
+// signatures, so it cannot be fooled by an upstream serving a forged header.
+type LightProber struct {
+	mu     sync.Mutex
+	client *light.Client
+}
+
+// NewLightProber creates a LightProber and fetches the trusted header from cfg.Primary.
+//
+// ctx is the context for the initial header download.
+// cfg is the root of trust of the light client.
+// With headers in cfg.StoreDir the client resumes from the latest of them, unless the trusted
+// header is newer.
+// Returns the prober, or an error if the configuration is invalid or the trusted header
+// does not match cfg.TrustedHash.
+func NewLightProber(ctx context.Context, cfg LightConfig) (*LightProber, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	var db dbm.DB = dbm.NewMemDB()
+	if cfg.StoreDir != "" {
+		var err error
+		if db, err = dbm.NewGoLevelDB("headers", cfg.StoreDir); err != nil {
+			return nil, fmt.Errorf("failed to open light client store: %v", err)
+		}
+	}
+	hash, _ := hex.DecodeString(cfg.TrustedHash)
+	client, err := light.NewHTTPClient(
+		ctx,
+		cfg.ChainId,
+		light.TrustOptions{Period: cfg.TrustingPeriod, Height: cfg.TrustedHeight, Hash: hash},
+		cfg.Primary,
+		cfg.Witnesses,
+		dbs.New(db, cfg.ChainId),
+	)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to start light client: %v", err)
+	}
+	return &LightProber{client: client}, nil
+}
+
+func (p *LightProber) Kind() string {
+	return KindCometBFT
+}
+
+// LatestBlock verifies and returns the primary's latest block.
+func (p *LightProber) LatestBlock(ctx context.Context) (Block, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lb, err := p.client.Update(ctx, time.Now())
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to verify latest block: %v", err)
+	}
+	if lb == nil {
+		// the primary has no block newer than the trusted one
+		if lb, err = p.client.TrustedLightBlock(0); err != nil {
+			return Block{}, err
+		}
+	}
+	return lightBlock(lb), nil
+}
+
+// BlockAt verifies and returns the block at height.
+func (p *LightProber) BlockAt(ctx context.Context, height int64) (Block, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lb, err := p.client.VerifyLightBlockAtHeight(ctx, height, time.Now())
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to verify block %d: %v", height, err)
+	}
+	return lightBlock(lb), nil
+}
+
+// lightBlock converts a verified light block; its hash is the block ID hash served by /block.
+func lightBlock(lb *types.LightBlock) Block {
+	return Block{Height: lb.Height, Hash: lb.Hash().String()}
+}
@@ -0,0 +1,129 @@
+package prober
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	rio "io"
+	"net/http"
+	"time"
+)
+
+const (
+	KindCometBFT = "cometbft"
+	KindEVM      = "evm"
+	KindBitcoin  = "bitcoin"
+)
+
+// Block is the height and hash of a block as reported by an upstream node.
+type Block struct {
+	Height int64
+	Hash   string
+}
+
+// Prober reads block data from a single upstream network.
+type Prober interface {
+	// Kind returns the RPC flavour spoken by the prober, e.g. "cometbft".
+	Kind() string
+	// LatestBlock returns the latest block known to the upstream node.
+	LatestBlock(ctx context.Context) (Block, error)
+	// BlockAt returns the block at the given height.
+	BlockAt(ctx context.Context, height int64) (Block, error)
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// NewProber creates a Prober for the given RPC kind and endpoint.
+//
+// kind is one of KindCometBFT, KindEVM or KindBitcoin.
+// endpoint is the upstream RPC url.
+// Returns the prober or an error if the kind is unknown.
+func NewProber(kind string, endpoint string) (Prober, error) {
+	switch kind {
+	case KindCometBFT, "":
+		return NewCometBFTProber(endpoint), nil
+	case KindEVM:
+		return NewEVMProber(endpoint), nil
+	case KindBitcoin:
+		return NewBitcoinProber(endpoint), nil
+	default:
+		return nil, fmt.Errorf("unknown prober kind: %s", kind)
+	}
+}
+
+type jsonRPCRequest struct {
+	JsonRPC string        `json:"jsonrpc"`
+	Id      interface{}   `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+type jsonRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type jsonRPCResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *jsonRPCError   `json:"error"`
+}
+
+// getJSON performs a GET request against url and decodes the response body into out.
+func getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := rio.ReadAll(resp.Body)
+		return fmt.Errorf("upstream returned non-200 status: %d, body: %s", resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// callJSONRPC posts a JSON-RPC request to endpoint and decodes the result into out.
+func callJSONRPC(ctx context.Context, endpoint string, version string, method string, params []interface{}, out interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(jsonRPCRequest{
+		JsonRPC: version,
+		Id:      1,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// bitcoind answers RPC errors with a non-200 status and a JSON body, so decode first
+	var rpcResp jsonRPCResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rpcResp); err != nil {
+		return fmt.Errorf("failed to decode %s response (status %d): %v", method, resp.StatusCode, err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("%s returned error %d: %s", method, rpcResp.Error.Code, rpcResp.Error.Message)
+	}
+	if len(rpcResp.Result) == 0 || string(rpcResp.Result) == "null" {
+		return fmt.Errorf("%s returned empty result", method)
+	}
+	return json.Unmarshal(rpcResp.Result, out)
+}
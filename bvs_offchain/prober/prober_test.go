@@ -0,0 +1,177 @@
+package prober
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newJSONRPCServer(t *testing.T, handle func(method string, params []interface{}) interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req jsonRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": req.Id, "result": handle(req.Method, req.Params)})
+	}))
+}
+
+func TestCometBFTProber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status":
+			fmt.Fprint(w, `{"result":{"sync_info":{"latest_block_height":"105"}}}`)
+		case "/block":
+			fmt.Fprintf(w, `{"result":{"block_id":{"hash":"HASH%s"}}}`, r.URL.Query().Get("height"))
+		}
+	}))
+	defer srv.Close()
+
+	p := NewCometBFTProber(srv.URL)
+	block, err := p.LatestBlock(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Block{Height: 105, Hash: "HASH105"}, block)
+
+	ok, err := Verify(context.Background(), p, Block{Height: 100, Hash: "HASH100"}, DefaultMaxLag)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = Verify(context.Background(), p, Block{Height: 100, Hash: "HASH101"}, DefaultMaxLag)
+	assert.NoError(t, err)
+	assert.False(t, ok, "hash mismatch")
+
+	ok, err = Verify(context.Background(), p, Block{Height: 90, Hash: "HASH90"}, DefaultMaxLag)
+	assert.NoError(t, err)
+	assert.False(t, ok, "block too old")
+}
+
+func TestEVMProber(t *testing.T) {
+	srv := newJSONRPCServer(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "eth_blockNumber":
+			return "0x1a"
+		case "eth_getBlockByNumber":
+			return map[string]string{"number": params[0].(string), "hash": "0xABC" + params[0].(string)}
+		}
+		return nil
+	})
+	defer srv.Close()
+
+	block, err := NewEVMProber(srv.URL).LatestBlock(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Block{Height: 26, Hash: "0xabc0x1a"}, block)
+}
+
+func TestBitcoinProber(t *testing.T) {
+	srv := newJSONRPCServer(t, func(method string, params []interface{}) interface{} {
+		switch method {
+		case "getblockcount":
+			return 840000
+		case "getblockhash":
+			return fmt.Sprintf("hash%v", params[0])
+		}
+		return nil
+	})
+	defer srv.Close()
+
+	block, err := NewBitcoinProber(srv.URL).LatestBlock(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Block{Height: 840000, Hash: "hash840000"}, block)
+}
+
+func TestParseResult(t *testing.T) {
+	block, err := ParseResult(FormatResult(Block{Height: 42, Hash: "ABC"}))
+	assert.NoError(t, err)
+	assert.Equal(t, Block{Height: 42, Hash: "ABC"}, block)
+
+	_, err = ParseResult("42-A-B")
+	assert.Error(t, err)
+}
+
+type staticProber struct {
+	head   int64
+	hashes map[int64]string
+	err    error
+}
+
+func (p *staticProber) Kind() string { return KindCometBFT }
+
+func (p *staticProber) LatestBlock(ctx context.Context) (Block, error) {
+	if p.err != nil {
+		return Block{}, p.err
+	}
+	return p.BlockAt(ctx, p.head)
+}
+
+func (p *staticProber) BlockAt(ctx context.Context, height int64) (Block, error) {
+	if p.err != nil {
+		return Block{}, p.err
+	}
+	return Block{Height: height, Hash: p.hashes[height]}, nil
+}
+
+func TestQuorumProber(t *testing.T) {
+	honest := map[int64]string{100: "A100", 101: "A101", 102: "A102"}
+	upstreams := []Upstream{
+		{Name: "a", Prober: &staticProber{head: 102, hashes: honest}},
+		{Name: "b", Prober: &staticProber{head: 101, hashes: honest}},
+		{Name: "liar", Prober: &staticProber{head: 102, hashes: map[int64]string{101: "B101", 102: "B102"}}},
+		{Name: "down", Prober: &staticProber{err: fmt.Errorf("connection refused")}},
+	}
+	q, err := NewQuorumProber(upstreams, 2)
+	assert.NoError(t, err)
+
+	var disagreed []string
+	q.OnDisagreement = func(upstream string, height int64, reason string) {
+		disagreed = append(disagreed, upstream)
+	}
+
+	block, err := q.LatestBlock(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, Block{Height: 102, Hash: "A102"}, block)
+	assert.ElementsMatch(t, []string{"down", "down", "liar"}, disagreed)
+
+	_, err = q.BlockAt(context.Background(), 103)
+	assert.Error(t, err, "upstreams disagree on an unknown block")
+
+	q, err = NewQuorumProber(upstreams, 3)
+	assert.NoError(t, err)
+	_, err = q.BlockAt(context.Background(), 101)
+	assert.Error(t, err, "only two honest upstreams")
+	assert.Equal(t, uint64(1), q.Disagreements()["liar"])
+
+	_, err = NewQuorumProber(upstreams, 5)
+	assert.Error(t, err)
+}
+
+func TestLightConfigValidate(t *testing.T) {
+	valid := LightConfig{
+		ChainId:        "sat-bbn-testnet1",
+		Primary:        "http://primary",
+		Witnesses:      []string{"http://witness"},
+		TrustedHeight:  100,
+		TrustedHash:    "7E3B2C3D9A8F5E4B1C0D2A3F4E5D6C7B8A9F0E1D2C3B4A5968778695A4B3C2D1",
+		TrustingPeriod: 168 * time.Hour,
+	}
+	assert.NoError(t, valid.Validate())
+
+	cases := map[string]func(c *LightConfig){
+		"no witnesses":    func(c *LightConfig) { c.Witnesses = nil },
+		"no chain id":     func(c *LightConfig) { c.ChainId = "" },
+		"short hash":      func(c *LightConfig) { c.TrustedHash = "7E3B" },
+		"non-hex hash":    func(c *LightConfig) { c.TrustedHash = "not-a-hash" },
+		"zero height":     func(c *LightConfig) { c.TrustedHeight = 0 },
+		"no trust period": func(c *LightConfig) { c.TrustingPeriod = 0 },
+	}
+	for name, mutate := range cases {
+		c := valid
+		mutate(&c)
+		assert.Error(t, c.Validate(), name)
+	}
+}
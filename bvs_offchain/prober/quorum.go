@@ -0,0 +1,167 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Upstream is a named Prober taking part in a quorum read.
+type Upstream struct {
+	Name   string
+	Prober Prober
+}
+
+// QuorumProber reads from several upstreams in parallel and only reports a block
+// that at least quorum of them agree on.
+type QuorumProber struct {
+	upstreams []Upstream
+	quorum    int
+
+	// OnDisagreement, if set, is called for every upstream that failed or reported
+	// a different hash than the agreed one.
+	OnDisagreement func(upstream string, height int64, reason string)
+
+	mu            sync.Mutex
+	disagreements map[string]uint64
+}
+
+// NewQuorumProber creates a QuorumProber over upstreams.
+//
+// quorum is the number of upstreams that must agree; 0 means a simple majority.
+// Returns the prober or an error if quorum cannot be met by the upstreams.
+func NewQuorumProber(upstreams []Upstream, quorum int) (*QuorumProber, error) {
+	if len(upstreams) == 0 {
+		return nil, fmt.Errorf("no upstreams configured")
+	}
+	if quorum == 0 {
+		quorum = len(upstreams)/2 + 1
+	}
+	if quorum < 0 || quorum > len(upstreams) {
+		return nil, fmt.Errorf("quorum %d out of range for %d upstreams", quorum, len(upstreams))
+	}
+	return &QuorumProber{
+		upstreams:     upstreams,
+		quorum:        quorum,
+		disagreements: make(map[string]uint64),
+	}, nil
+}
+
+func (q *QuorumProber) Kind() string {
+	return q.upstreams[0].Prober.Kind()
+}
+
+// Disagreements returns how often each upstream failed or disagreed with the quorum.
+func (q *QuorumProber) Disagreements() map[string]uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	out := make(map[string]uint64, len(q.disagreements))
+	for name, cnt := range q.disagreements {
+		out[name] = cnt
+	}
+	return out
+}
+
+type upstreamResult struct {
+	name  string
+	block Block
+	err   error
+}
+
+// fanOut calls fn on every upstream in parallel and collects the results in upstream order.
+func (q *QuorumProber) fanOut(ctx context.Context, fn func(ctx context.Context, p Prober) (Block, error)) []upstreamResult {
+	results := make([]upstreamResult, len(q.upstreams))
+	var wg sync.WaitGroup
+	for i, u := range q.upstreams {
+		wg.Add(1)
+		go func(i int, u Upstream) {
+			defer wg.Done()
+			block, err := fn(ctx, u.Prober)
+			results[i] = upstreamResult{name: u.Name, block: block, err: err}
+		}(i, u)
+	}
+	wg.Wait()
+	return results
+}
+
+// LatestBlock returns the highest block at least quorum upstreams have reached and agree on.
+//
+// Upstreams are one or two blocks apart in normal operation, so the height is picked as the
+// quorum-th highest reported head and then read back from every upstream.
+func (q *QuorumProber) LatestBlock(ctx context.Context) (Block, error) {
+	heads := q.fanOut(ctx, func(ctx context.Context, p Prober) (Block, error) {
+		return p.LatestBlock(ctx)
+	})
+
+	heights := make([]int64, 0, len(heads))
+	for _, head := range heads {
+		if head.err != nil {
+			q.disagree(head.name, 0, head.err.Error())
+			continue
+		}
+		heights = append(heights, head.block.Height)
+	}
+	if len(heights) < q.quorum {
+		return Block{}, fmt.Errorf("only %d of %d upstreams reported a head, quorum is %d", len(heights), len(q.upstreams), q.quorum)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+
+	return q.BlockAt(ctx, heights[q.quorum-1])
+}
+
+// BlockAt returns the block at height if at least quorum upstreams report the same hash.
+func (q *QuorumProber) BlockAt(ctx context.Context, height int64) (Block, error) {
+	results := q.fanOut(ctx, func(ctx context.Context, p Prober) (Block, error) {
+		block, err := p.BlockAt(ctx, height)
+		if err == nil && block.Hash == "" {
+			err = fmt.Errorf("block %d has no hash", height)
+		}
+		return block, err
+	})
+
+	votes := make(map[string][]string)
+	var agreed string
+	for _, r := range results {
+		if r.err != nil {
+			continue
+		}
+		hash := strings.ToLower(r.block.Hash)
+		votes[hash] = append(votes[hash], r.name)
+		if len(votes[hash]) > len(votes[agreed]) {
+			agreed = hash
+		}
+	}
+
+	for _, r := range results {
+		switch {
+		case r.err != nil:
+			q.disagree(r.name, height, r.err.Error())
+		case strings.ToLower(r.block.Hash) != agreed:
+			q.disagree(r.name, height, fmt.Sprintf("hash %s differs from %s", r.block.Hash, agreed))
+		}
+	}
+
+	if len(votes[agreed]) < q.quorum {
+		return Block{}, fmt.Errorf("no quorum on block %d: %d of %d upstreams agree, quorum is %d", height, len(votes[agreed]), len(q.upstreams), q.quorum)
+	}
+	for _, r := range results {
+		if r.err == nil && strings.ToLower(r.block.Hash) == agreed {
+			return r.block, nil
+		}
+	}
+	return Block{}, fmt.Errorf("no quorum on block %d", height)
+}
+
+// disagree records that upstream failed or disagreed at height.
+func (q *QuorumProber) disagree(upstream string, height int64, reason string) {
+	q.mu.Lock()
+	q.disagreements[upstream]++
+	q.mu.Unlock()
+
+	if q.OnDisagreement != nil {
+		q.OnDisagreement(upstream, height, reason)
+	}
+}
@@ -0,0 +1,56 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultMaxLag is the number of blocks a performer's block may trail the attester's head.
+const DefaultMaxLag = 10
+
+// FormatResult encodes a block as the performer result string "blockNumber-blockHash".
+func FormatResult(block Block) string {
+	return fmt.Sprintf("%d-%s", block.Height, block.Hash)
+}
+
+// ParseResult decodes a performer result string "blockNumber-blockHash".
+//
+// result is the string submitted by the performer.
+// Returns the decoded block or an error if the format is invalid.
+func ParseResult(result string) (Block, error) {
+	parts := strings.Split(result, "-")
+	if len(parts) != 2 {
+		return Block{}, fmt.Errorf("invalid performer data format")
+	}
+
+	height, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Block{}, fmt.Errorf("failed to parse block number: %v", err)
+	}
+
+	return Block{Height: height, Hash: parts[1]}, nil
+}
+
+// Verify checks a claimed block against the upstream behind p.
+//
+// The claim is valid if the upstream has the same hash at the claimed height
+// and the claimed height is no more than maxLag blocks behind the upstream head.
+// Returns whether the claim is valid, or an error if the upstream could not be read.
+func Verify(ctx context.Context, p Prober, claimed Block, maxLag int64) (bool, error) {
+	current, err := p.LatestBlock(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch current block data: %v", err)
+	}
+
+	actual, err := p.BlockAt(ctx, claimed.Height)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch block data: %v", err)
+	}
+
+	isBlockValid := strings.EqualFold(actual.Hash, claimed.Hash)
+	isBlockRecent := current.Height-claimed.Height <= maxLag
+
+	return isBlockValid && isBlockRecent, nil
+}
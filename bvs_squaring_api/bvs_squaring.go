@@ -3,10 +3,18 @@ package BvsSquaringApi
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
 
 	wasmtypes "github.com/CosmWasm/wasmd/x/wasm/types"
 	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cosmos/cosmos-sdk/client/tx"
 	sdktypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
 	"github.com/satlayer/satlayer-api/chainio/io"
 	"github.com/satlayer/satlayer-api/chainio/types"
 )
@@ -15,10 +23,65 @@ type BVSSquaring interface {
 	BindClient(string)
 	CreateNewTask(context.Context, string) (*coretypes.ResultTx, error)
 	RespondToTask(ctx context.Context, taskId int64, result int64, operators string) (*coretypes.ResultTx, error)
+	RespondToTasks(ctx context.Context, responses []types.RespondToTask) (*coretypes.ResultTx, error)
 	GetTaskInput(int64) (*wasmtypes.QuerySmartContractStateResponse, error)
 	GetTaskResult(int64) (*wasmtypes.QuerySmartContractStateResponse, error)
 }
 
+// sendMu serializes the transactions of this process. They are all signed by one account, and
+// each one is sent with the account sequence ChainIO reports once the previous one is included.
+var sendMu sync.Mutex
+
+// TxTimeout is how long a broadcast transaction is waited for, and how long the next one waits
+// for the account sequence to move past it if it was not included in time.
+const TxTimeout = 60 * time.Second
+
+// ErrTxNotConfirmed is returned when a broadcast transaction was not included within TxTimeout,
+// or the next one gave up waiting for it; it may still be included later.
+var ErrTxNotConfirmed = errors.New("transaction not confirmed")
+
+// pendingTx is the transaction RespondToTasks stopped waiting for while it may still be in the
+// mempool, or nil. It is guarded by sendMu.
+var pendingTx *unconfirmedTx
+
+// unconfirmedTx is a broadcast transaction that was not included within TxTimeout.
+type unconfirmedTx struct {
+	hash     []byte
+	sequence uint64
+}
+
+// msgIndexLog finds the index of the failed message in the ABCI log of a transaction, which the
+// SDK writes as "failed to execute message; message index: <i>: <error>".
+var msgIndexLog = regexp.MustCompile(`failed to execute message; message index: (\d+)`)
+
+// TxError is a transaction that CheckTx rejected or that failed when it was executed.
+type TxError struct {
+	Hash      []byte
+	Code      uint32
+	Codespace string
+	Log       string
+	// MsgIndex is the index of the message that failed, -1 if no message ran or the log does not name it
+	MsgIndex int
+}
+
+func (e *TxError) Error() string {
+	if e.Hash == nil {
+		return fmt.Sprintf("transaction rejected with code %d (%s): %s", e.Code, e.Codespace, e.Log)
+	}
+	return fmt.Sprintf("transaction %X failed with code %d (%s): %s", e.Hash, e.Code, e.Codespace, e.Log)
+}
+
+// newTxError returns the error of a transaction with a non-zero ABCI code.
+func newTxError(hash []byte, code uint32, codespace string, log string) *TxError {
+	err := &TxError{Hash: hash, Code: code, Codespace: codespace, Log: log, MsgIndex: -1}
+	if match := msgIndexLog.FindStringSubmatch(log); match != nil {
+		if i, convErr := strconv.Atoi(match[1]); convErr == nil {
+			err.MsgIndex = i
+		}
+	}
+	return err
+}
+
 type bvsSquaringImpl struct {
 	io             io.ChainIO
 	executeOptions *types.ExecuteOptions
@@ -57,6 +120,11 @@ func (a *bvsSquaringImpl) CreateNewTask(ctx context.Context, input string) (*cor
 		return nil, err
 	}
 
+	sendMu.Lock()
+	defer sendMu.Unlock()
+	if err := a.awaitPendingTx(ctx); err != nil {
+		return nil, err
+	}
 	return a.io.SendTransaction(ctx, *a.executeOptions)
 }
 
@@ -76,9 +144,165 @@ func (a *bvsSquaringImpl) RespondToTask(ctx context.Context, taskId int64, resul
 		return nil, err
 	}
 
+	sendMu.Lock()
+	defer sendMu.Unlock()
+	if err := a.awaitPendingTx(ctx); err != nil {
+		return nil, err
+	}
 	return a.io.SendTransaction(ctx, *a.executeOptions)
 }
 
+// RespondToTasks responds to several tasks in one transaction, with one execute message per task.
+//
+// ChainIO only sends single messages, so the transaction is built here, but with the account
+// number and sequence ChainIO reports, and never while another transaction of this process is
+// in flight. The transaction is atomic: if any message fails, no task is responded to, and the
+// *TxError carries the ABCI code and the index of the failed message. With Simulate set, the
+// gas limit is the simulated gas scaled by the configured gas adjustment, and a message that
+// fails the simulation is reported as a *TxError without broadcasting; otherwise it is the
+// configured gas per message. If the transaction is not included within TxTimeout, the next
+// transaction waits for the account sequence to move past it, see awaitPendingTx.
+// Returns the included transaction, a *TxError if it was rejected or failed, ErrTxNotConfirmed if
+// it was not included in time, or an error if it could not be built or broadcast.
+func (a *bvsSquaringImpl) RespondToTasks(ctx context.Context, responses []types.RespondToTask) (*coretypes.ResultTx, error) {
+	if len(responses) == 0 {
+		return nil, fmt.Errorf("no task responses")
+	}
+	sendMu.Lock()
+	defer sendMu.Unlock()
+	if err := a.awaitPendingTx(ctx); err != nil {
+		return nil, err
+	}
+	account, err := a.io.GetCurrentAccount()
+	if err != nil {
+		return nil, err
+	}
+	clientCtx := a.io.GetClientCtx().WithFromAddress(account.GetAddress())
+	key, err := clientCtx.Keyring.KeyByAddress(account.GetAddress())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find signing key: %v", err)
+	}
+
+	msgs := make([]sdktypes.Msg, 0, len(responses))
+	for _, response := range responses {
+		msgBytes, err := json.Marshal(types.RespondToTaskReq{RespondToTask: response})
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, &wasmtypes.MsgExecuteContract{
+			Sender:   account.GetAddress().String(),
+			Contract: a.executeOptions.ContractAddr,
+			Msg:      msgBytes,
+		})
+	}
+
+	txf := tx.Factory{}.
+		WithChainID(clientCtx.ChainID).
+		WithKeybase(clientCtx.Keyring).
+		WithTxConfig(clientCtx.TxConfig).
+		WithAccountNumber(account.GetAccountNumber()).
+		WithSequence(account.GetSequence()).
+		WithGas(a.executeOptions.Gas * uint64(len(msgs))).
+		WithGasPrices(a.executeOptions.GasPrice.String()).
+		WithMemo(a.executeOptions.Memo).
+		WithSignMode(signing.SignMode_SIGN_MODE_DIRECT)
+	if a.executeOptions.Simulate {
+		simf := txf.WithGasAdjustment(a.executeOptions.GasAdjustment).WithFromName(key.Name).WithSimulateAndExecute(true)
+		_, gas, err := tx.CalculateGas(clientCtx, simf, msgs...)
+		if err != nil {
+			if msgIndexLog.MatchString(err.Error()) {
+				return nil, newTxError(nil, 0, "simulate", err.Error())
+			}
+			return nil, fmt.Errorf("failed to simulate transaction: %v", err)
+		}
+		txf = txf.WithGas(gas)
+	}
+	txBuilder, err := txf.BuildUnsignedTx(msgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transaction: %v", err)
+	}
+	if err := tx.Sign(ctx, txf, key.Name, txBuilder, true); err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %v", err)
+	}
+	txBytes, err := clientCtx.TxConfig.TxEncoder()(txBuilder.GetTx())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode transaction: %v", err)
+	}
+
+	broadcast, err := clientCtx.Client.BroadcastTxSync(ctx, txBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to broadcast transaction: %v", err)
+	}
+	if broadcast.Code != 0 {
+		return nil, newTxError(nil, broadcast.Code, broadcast.Codespace, broadcast.Log)
+	}
+	res, err := waitForTx(ctx, clientCtx.Client, broadcast.Hash)
+	if res == nil && err != nil {
+		// the transaction holds the sequence until it is included or dropped
+		pendingTx = &unconfirmedTx{hash: broadcast.Hash, sequence: account.GetSequence()}
+	}
+	return res, err
+}
+
+// awaitPendingTx waits until the account sequence moved past pendingTx, so the next transaction
+// is not signed with the sequence of one that may still be in the mempool.
+//
+// After TxTimeout the transaction is taken as dropped. If it is still included later, the chain
+// rejects the next transaction with a sequence mismatch, and its caller retries it.
+// It must be called with sendMu held.
+// Returns ErrTxNotConfirmed only if ctx is done.
+func (a *bvsSquaringImpl) awaitPendingTx(ctx context.Context) error {
+	if pendingTx == nil {
+		return nil
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, TxTimeout)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		account, err := a.io.GetCurrentAccount()
+		if err == nil && account.GetSequence() > pendingTx.sequence {
+			pendingTx = nil
+			return nil
+		}
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return fmt.Errorf("%w: transaction %X still pending: %v", ErrTxNotConfirmed, pendingTx.hash, ctx.Err())
+			}
+			pendingTx = nil
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// waitForTx polls for a broadcast transaction until it is included or TxTimeout passed.
+func waitForTx(ctx context.Context, client interface {
+	Tx(ctx context.Context, hash []byte, prove bool) (*coretypes.ResultTx, error)
+}, hash []byte) (*coretypes.ResultTx, error) {
+	ctx, cancel := context.WithTimeout(ctx, TxTimeout)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: transaction %X: %v", ErrTxNotConfirmed, hash, ctx.Err())
+		case <-ticker.C:
+		}
+		res, err := client.Tx(ctx, hash, false)
+		if err != nil {
+			// not included yet
+			continue
+		}
+		if res.TxResult.Code != 0 {
+			return res, newTxError(hash, res.TxResult.Code, res.TxResult.Codespace, res.TxResult.Log)
+		}
+		return res, nil
+	}
+}
+
 func (a *bvsSquaringImpl) GetTaskInput(taskId int64) (*wasmtypes.QuerySmartContractStateResponse, error) {
 	msg := types.GetTaskInputReq{
 		GetTaskInput: types.GetTaskInput{
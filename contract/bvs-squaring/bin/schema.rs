@@ -1,11 +1,12 @@
 use cosmwasm_schema::write_api;
 
-use bvs_squaring::msg::{ExecuteMsg, InstantiateMsg, QueryMsg};
+use bvs_squaring::msg::{ExecuteMsg, InstantiateMsg, MigrateMsg, QueryMsg};
 
 fn main() {
     write_api! {
         instantiate: InstantiateMsg,
         execute: ExecuteMsg,
         query: QueryMsg,
+        migrate: MigrateMsg,
     }
 }
@@ -1,6 +1,6 @@
 use crate::{
     error::ContractError,
-    msg::{ExecuteMsg, InstantiateMsg, QueryMsg},
+    msg::{ExecuteMsg, InstantiateMsg, MigrateMsg, QueryMsg},
     state::{
         AGGREGATOR, BVS_DRIVER, CREATED_TASKS, MAX_ID, OPERATOR_MAX_SCORE, OPERATOR_SCORE,
         RESPONDED_TASKS, STATE_BANK,
@@ -11,11 +11,15 @@ use cosmwasm_std::{
     entry_point, to_json_binary, Addr, Binary, CosmosMsg, Deps, DepsMut, Env, Event, MessageInfo,
     Response, WasmMsg,
 };
-use cw2::set_contract_version;
+use cw2::{get_contract_version, set_contract_version};
 
 const CONTRACT_NAME: &str = env!("CARGO_PKG_NAME");
 const CONTRACT_VERSION: &str = env!("CARGO_PKG_VERSION");
 
+// results the aggregator submits for tasks that expired without consensus
+const RESULT_INSUFFICIENT_ATTESTATIONS: i64 = -2;
+const RESULT_SPLIT_VOTE: i64 = -3;
+
 #[cfg_attr(not(feature = "library"), entry_point)]
 pub fn instantiate(
     deps: DepsMut,
@@ -33,6 +37,37 @@ pub fn instantiate(
     Ok(response)
 }
 
+// Migrates an instance of an earlier version, which must have been instantiated with an admin.
+// Version 2.1.0 leaves the scores unchanged for RESULT_INSUFFICIENT_ATTESTATIONS and
+// RESULT_SPLIT_VOTE, and keeps the state of earlier versions as it is.
+#[cfg_attr(not(feature = "library"), entry_point)]
+pub fn migrate(deps: DepsMut, _env: Env, _msg: MigrateMsg) -> Result<Response, ContractError> {
+    let stored = get_contract_version(deps.storage)?;
+    if stored.contract != CONTRACT_NAME
+        || parse_version(&stored.version) > parse_version(CONTRACT_VERSION)
+    {
+        return Err(ContractError::InvalidMigration {
+            contract: stored.contract,
+            version: stored.version,
+        });
+    }
+    set_contract_version(deps.storage, CONTRACT_NAME, CONTRACT_VERSION)?;
+
+    let response = Response::new()
+        .add_attribute("method", "migrate")
+        .add_attribute("from_version", stored.version)
+        .add_attribute("to_version", CONTRACT_VERSION);
+    Ok(response)
+}
+
+// Parses a "major.minor.patch" version, so versions compare by their numbers.
+fn parse_version(version: &str) -> Vec<u64> {
+    version
+        .split('.')
+        .map(|part| part.parse().unwrap_or(0))
+        .collect()
+}
+
 #[entry_point]
 pub fn execute(
     deps: DepsMut,
@@ -130,27 +165,31 @@ fn respond_to_task(
     // fetch operator address for the task
     let operator = CREATED_TASKS.load(deps.storage, task_id)?;
 
-    // update the operator's score based on the result
-    OPERATOR_SCORE.update(
-        deps.storage,
-        operator.clone(),
-        |score| -> Result<_, ContractError> {
-            let current_score = score.unwrap_or(0); // Default score is 0 if not set
-            let updated_score = if result == 1 {
-                current_score + 1
-            } else {
-                current_score - 1
-            };
-            Ok(updated_score)
-        },
-    )?;
-
-    // increment operator's max score
-    OPERATOR_MAX_SCORE.update(
-        deps.storage,
-        operator.clone(),
-        |max_score| -> Result<_, ContractError> { Ok(max_score.unwrap_or(0) + 1) },
-    )?;
+    // a task that expired with too few or split attestations says nothing about the operator,
+    // so it leaves the score alone
+    if result != RESULT_INSUFFICIENT_ATTESTATIONS && result != RESULT_SPLIT_VOTE {
+        // update the operator's score based on the result
+        OPERATOR_SCORE.update(
+            deps.storage,
+            operator.clone(),
+            |score| -> Result<_, ContractError> {
+                let current_score = score.unwrap_or(0); // Default score is 0 if not set
+                let updated_score = if result == 1 {
+                    current_score + 1
+                } else {
+                    current_score - 1
+                };
+                Ok(updated_score)
+            },
+        )?;
+
+        // increment operator's max score
+        OPERATOR_MAX_SCORE.update(
+            deps.storage,
+            operator.clone(),
+            |max_score| -> Result<_, ContractError> { Ok(max_score.unwrap_or(0) + 1) },
+        )?;
+    }
 
     // emit event
     let event = Event::new("TaskResponded")
@@ -419,6 +458,87 @@ mod tests {
         );
     }
 
+    #[test]
+    fn respond_to_task_without_consensus() {
+        let mut deps = mock_dependencies();
+        let env = mock_env();
+        let info = mock_info("creator", &[]);
+        let msg = InstantiateMsg {
+            aggregator: Addr::unchecked("aggregator"),
+            state_bank: Addr::unchecked("state_bank"),
+            bvs_driver: Addr::unchecked("bvs_driver"),
+        };
+        instantiate(deps.as_mut(), env.clone(), info.clone(), msg).unwrap();
+
+        let aggregator_info = mock_info("aggregator", &[]);
+        // Expire one task with too few attestations and one with a split vote
+        for (task_id, result) in [(1, -2), (2, -3)] {
+            let create_msg = ExecuteMsg::CreateNewTask {
+                input: Addr::unchecked("operator"),
+            };
+            execute(deps.as_mut(), env.clone(), info.clone(), create_msg).unwrap();
+
+            let respond_msg = ExecuteMsg::RespondToTask { task_id, result };
+            execute(
+                deps.as_mut(),
+                env.clone(),
+                aggregator_info.clone(),
+                respond_msg,
+            )
+            .unwrap();
+            assert_eq!(
+                RESPONDED_TASKS.load(deps.as_ref().storage, task_id).unwrap(),
+                result
+            );
+        }
+
+        // Verify that neither result touched the operator's score
+        let operator_addr = Addr::unchecked("operator");
+        assert_eq!(
+            OPERATOR_SCORE
+                .may_load(deps.as_ref().storage, operator_addr.clone())
+                .unwrap(),
+            None
+        );
+        assert_eq!(
+            OPERATOR_MAX_SCORE
+                .may_load(deps.as_ref().storage, operator_addr)
+                .unwrap(),
+            None
+        );
+    }
+
+    #[test]
+    fn test_migrate() {
+        let mut deps = mock_dependencies();
+        let env = mock_env();
+        let info = mock_info("creator", &[]);
+        let msg = InstantiateMsg {
+            aggregator: Addr::unchecked("aggregator"),
+            state_bank: Addr::unchecked("state_bank"),
+            bvs_driver: Addr::unchecked("bvs_driver"),
+        };
+        instantiate(deps.as_mut(), env.clone(), info, msg).unwrap();
+
+        // An instance of the previous version is migrated
+        set_contract_version(deps.as_mut().storage, CONTRACT_NAME, "2.0.0").unwrap();
+        migrate(deps.as_mut(), env.clone(), MigrateMsg {}).unwrap();
+        let version = get_contract_version(deps.as_ref().storage).unwrap();
+        assert_eq!(version.version, CONTRACT_VERSION);
+        assert_eq!(
+            AGGREGATOR.load(deps.as_ref().storage).unwrap(),
+            Addr::unchecked("aggregator")
+        );
+
+        // A newer version or another contract is not
+        set_contract_version(deps.as_mut().storage, CONTRACT_NAME, "10.0.0").unwrap();
+        let err = migrate(deps.as_mut(), env.clone(), MigrateMsg {}).unwrap_err();
+        assert!(matches!(err, ContractError::InvalidMigration { .. }));
+        set_contract_version(deps.as_mut().storage, "other-contract", "1.0.0").unwrap();
+        let err = migrate(deps.as_mut(), env, MigrateMsg {}).unwrap_err();
+        assert!(matches!(err, ContractError::InvalidMigration { .. }));
+    }
+
     #[test]
     fn respond_to_task_unauthorized() {
         let mut deps = mock_dependencies();
@@ -14,4 +14,7 @@ pub enum ContractError {
 
     #[error("BVSSquaring: no value found")]
     NoValueFound {},
+
+    #[error("BVSSquaring: cannot migrate from {contract} {version}")]
+    InvalidMigration { contract: String, version: String },
 }
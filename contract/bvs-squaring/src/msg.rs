@@ -16,6 +16,9 @@ pub enum ExecuteMsg {
     ExecuteBvsOffchain { task_id: String },
 }
 
+#[cw_serde]
+pub struct MigrateMsg {}
+
 #[cw_serde]
 #[derive(QueryResponses)]
 pub enum QueryMsg {
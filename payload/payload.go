@@ -0,0 +1,122 @@
+package payload
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cryptotypes "github.com/cosmos/cosmos-sdk/crypto/types"
+	"github.com/satlayer/satlayer-api/signer"
+)
+
+const (
+	// Version is the version of the signed message encoding.
+	Version = 1
+	// Domain separates operator submissions from any other message signed with the same key.
+	Domain = "satrpc/operator-submission"
+
+	RolePerformer = "performer"
+	RoleAttester  = "attester"
+)
+
+// Submission is the body an operator posts to the aggregator.
+type Submission struct {
+	Version   int    `json:"version" binding:"required"`
+	TaskId    uint64 `json:"taskID" binding:"required"`
+	Result    string `json:"result" binding:"required"`
+	Timestamp int64  `json:"timestamp" binding:"required"`
+	Nonce     uint64 `json:"nonce" binding:"required"`
+	Signature string `json:"signature" binding:"required"`
+	PubKey    string `json:"pubKey" binding:"required"`
+	Role      string `json:"role" binding:"required"`
+	// Network is the network the task targets, empty for the default network.
+	Network string `json:"network,omitempty"`
+}
+
+// Message is the canonical content an operator signs for a submission.
+//
+// Every field of the submission except the signature is bound, together with the
+// domain, the chain ID and the BVS hash, so a signature cannot be replayed under a
+// different role, key, task, chain or BVS.
+type Message struct {
+	Version   int    `json:"version"`
+	Domain    string `json:"domain"`
+	ChainId   string `json:"chainId"`
+	BvsHash   string `json:"bvsHash"`
+	TaskId    uint64 `json:"taskId"`
+	Role      string `json:"role"`
+	Result    string `json:"result"`
+	PubKey    string `json:"pubKey"`
+	Timestamp int64  `json:"timestamp"`
+	Nonce     uint64 `json:"nonce"`
+	Network   string `json:"network,omitempty"`
+}
+
+// Signer signs raw bytes, e.g. the signer of a ChainIO.
+type Signer interface {
+	Sign(msg []byte) (string, error)
+}
+
+// Message returns the message the submission's signature covers.
+//
+// chainId and bvsHash identify the deployment the submission is for.
+func (s *Submission) Message(chainId string, bvsHash string) Message {
+	return Message{
+		Version:   s.Version,
+		Domain:    Domain,
+		ChainId:   chainId,
+		BvsHash:   bvsHash,
+		TaskId:    s.TaskId,
+		Role:      s.Role,
+		Result:    s.Result,
+		PubKey:    s.PubKey,
+		Timestamp: s.Timestamp,
+		Nonce:     s.Nonce,
+		Network:   s.Network,
+	}
+}
+
+// Bytes returns the canonical encoding of the message.
+//
+// The encoding is JSON with the fields in declaration order. It is deterministic
+// and, unlike a separator-joined string, unambiguous for any result value.
+func (m Message) Bytes() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// Sign fills in the version and signature of the submission.
+//
+// s is the signer of the operator key matching s.PubKey.
+// chainId and bvsHash identify the deployment the submission is for.
+// Returns an error if signing fails.
+func (s *Submission) Sign(signer Signer, chainId string, bvsHash string) error {
+	s.Version = Version
+	msgBytes, err := s.Message(chainId, bvsHash).Bytes()
+	if err != nil {
+		return err
+	}
+	signature, err := signer.Sign(msgBytes)
+	if err != nil {
+		return err
+	}
+	s.Signature = signature
+	return nil
+}
+
+// Verify checks the version and signature of the submission.
+//
+// pubKey is the key decoded from s.PubKey.
+// chainId and bvsHash identify the deployment the verifier serves.
+// Returns an error if the version is unsupported or the signature is invalid.
+func (s *Submission) Verify(pubKey cryptotypes.PubKey, chainId string, bvsHash string) error {
+	if s.Version != Version {
+		return fmt.Errorf("unsupported payload version %d", s.Version)
+	}
+	msgBytes, err := s.Message(chainId, bvsHash).Bytes()
+	if err != nil {
+		return err
+	}
+	if isValid, err := signer.VerifySignature(pubKey, msgBytes, s.Signature); err != nil || !isValid {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
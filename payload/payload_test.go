@@ -0,0 +1,54 @@
+package payload
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMessageBytes(t *testing.T) {
+	sub := Submission{
+		Version:   Version,
+		TaskId:    7,
+		Result:    "100-ABCD",
+		Timestamp: 1700000000,
+		Nonce:     42,
+		PubKey:    "pk",
+		Role:      RolePerformer,
+	}
+	got, err := sub.Message("sat-bbn-testnet1", "bvs").Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"version":1,"domain":"satrpc/operator-submission","chainId":"sat-bbn-testnet1","bvsHash":"bvs","taskId":7,"role":"performer","result":"100-ABCD","pubKey":"pk","timestamp":1700000000,"nonce":42}`
+	if string(got) != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+
+	// every bound field must change the signed bytes
+	variants := map[string]func(s *Submission) (string, string){
+		"role":    func(s *Submission) (string, string) { s.Role = RoleAttester; return "sat-bbn-testnet1", "bvs" },
+		"pubKey":  func(s *Submission) (string, string) { s.PubKey = "other"; return "sat-bbn-testnet1", "bvs" },
+		"nonce":   func(s *Submission) (string, string) { s.Nonce++; return "sat-bbn-testnet1", "bvs" },
+		"network": func(s *Submission) (string, string) { s.Network = "ethereum"; return "sat-bbn-testnet1", "bvs" },
+		"chain":   func(s *Submission) (string, string) { return "other-chain", "bvs" },
+		"bvs":     func(s *Submission) (string, string) { return "sat-bbn-testnet1", "other" },
+	}
+	for name, mutate := range variants {
+		s := sub
+		chainId, bvsHash := mutate(&s)
+		b, err := s.Message(chainId, bvsHash).Bytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(b, got) {
+			t.Errorf("%s: message unchanged", name)
+		}
+	}
+}
+
+func TestVerifyRejectsUnknownVersion(t *testing.T) {
+	sub := Submission{Version: Version + 1}
+	if err := sub.Verify(nil, "sat-bbn-testnet1", "bvs"); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	}
+}